@@ -0,0 +1,64 @@
+package dlidparser
+
+// Address is a postal address as written on the card.  The standard allows
+// two street lines; most jurisdictions only ever use the first.
+type Address struct {
+	street  string
+	street2 string
+	city    string
+	state   string
+	postal  PostalCode
+}
+
+func (a *Address) SetStreet(s string) {
+	a.street = s
+}
+
+func (a Address) Street() string {
+	return a.street
+}
+
+func (a *Address) SetStreet2(s string) {
+	a.street2 = s
+}
+
+func (a Address) Street2() string {
+	return a.street2
+}
+
+func (a *Address) SetCity(s string) {
+	a.city = s
+}
+
+func (a Address) City() string {
+	return a.city
+}
+
+func (a *Address) SetState(s string) {
+	a.state = s
+}
+
+func (a Address) State() string {
+	return a.state
+}
+
+func (a *Address) SetPostal(s string) {
+	a.postal = ParsePostalCode(s)
+}
+
+// Postal returns the postal code in its canonical form.
+func (a Address) Postal() string {
+	return a.postal.String()
+}
+
+func (a *Address) SetPostalCode(p PostalCode) {
+	a.postal = p
+}
+
+func (a Address) PostalCode() PostalCode {
+	return a.postal
+}
+
+func (a Address) IsZero() bool {
+	return a == Address{}
+}
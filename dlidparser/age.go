@@ -0,0 +1,78 @@
+package dlidparser
+
+import (
+	"time"
+)
+
+// These methods all work in calendar days.  The reference time is converted
+// to a date using its own location, so pass a time in the jurisdiction's time
+// zone (eg. time.Now().In(location)) to get the answer that the jurisdiction
+// would give.  A card scanned at 11pm in Los Angeles on the day before
+// someone's 21st birthday is already the birthday in UTC.
+
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// AgeOn returns the licencee's age in whole years on the given day, and
+// whether the date of birth was known.  Anyone born on the 29th of February
+// has their birthday on the 1st of March in non-leap years, as with
+// Under18Until and friends.
+func (d *DLIDLicense) AgeOn(t time.Time) (age int, ok bool) {
+
+	if !d.dateOfBirth.IsPresent() {
+		return 0, false
+	}
+
+	day := calendarDay(t)
+
+	age = day.Year() - d.dateOfBirth.Year()
+
+	if d.dateOfBirth.AddDate(age, 0, 0).After(day) {
+		age--
+	}
+
+	return age, true
+}
+
+// IsOfAge reports whether the licencee had reached the given age on the given
+// day.  The card's own under 18, 19 and 21 dates are used where it has them
+// and they can be read; otherwise the age is worked out from the date of
+// birth.  Licences without a date of birth are never of age.
+func (d *DLIDLicense) IsOfAge(years int, on time.Time) bool {
+
+	var until Date
+
+	switch years {
+	case 18:
+		until = d.Under18Until()
+	case 19:
+		until = d.Under19Until()
+	case 21:
+		until = d.Under21Until()
+	}
+
+	if !until.IsPresent() {
+		until = d.ageUntil(Date{}, years)
+	}
+
+	if !until.IsPresent() {
+		return false
+	}
+
+	return !calendarDay(on).Before(until.Time)
+}
+
+// IsExpiredOn reports whether the licence had expired on the given day.  A
+// licence is valid up to and including its expiry date, plus the number of
+// days' grace given; some jurisdictions accept recently expired cards as
+// identification.  Licences without a readable expiry date are treated as
+// expired, because there's no way to show that they aren't.
+func (d *DLIDLicense) IsExpiredOn(t time.Time, graceDays int) bool {
+
+	if !d.expiryDate.IsPresent() {
+		return true
+	}
+
+	return calendarDay(t).After(d.expiryDate.AddDate(0, 0, graceDays))
+}
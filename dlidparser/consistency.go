@@ -0,0 +1,78 @@
+package dlidparser
+
+// CheckConsistency compares the issuer named in the header against the rest
+// of the licence.  A genuine card can only disagree with itself if the
+// jurisdiction has made a mistake, which happens, but a home-made barcode
+// is far more likely to get these details wrong.  Each mismatch is reported
+// as a Warning.
+func CheckConsistency(license *DLIDLicense) (warnings []Warning) {
+
+	issuer, ok := LookupIssuer(license.IssuerId())
+
+	if !ok {
+		return []Warning{newWarning(WarningUnknownIssuer, "", "Issuer "+license.IssuerId()+" is not in the registry")}
+	}
+
+	if len(issuer.Code()) > 0 && len(license.State()) > 0 && issuer.Code() != license.State() {
+		warnings = append(warnings, newWarning(WarningIssuerState, "DAJ", "State "+license.State()+" does not match issuer "+issuer.Name()))
+	}
+
+	// Only version 3 onwards has a country element, and only those versions
+	// choose their date format based on it.
+
+	if _, ok := license.Element("DCG"); !ok {
+		return
+	}
+
+	if len(issuer.Country()) > 0 && len(license.Country()) > 0 && issuer.Country() != license.Country() {
+		warnings = append(warnings, newWarning(WarningIssuerCountry, "DCG", "Country "+license.Country()+" does not match issuer "+issuer.Name()))
+	}
+
+	// US issuers should write MMDDCCYY dates and everyone else CCYYMMDD.  A
+	// date that only makes sense in the other format was written by someone
+	// who didn't know which country they were pretending to be.
+
+	usDates := issuer.Country() == "USA"
+
+	for _, element := range []string{"DBB", "DBA", "DBD"} {
+		data, ok := license.Element(element)
+
+		if !ok {
+			continue
+		}
+
+		if usDates && !isDateMMDDCCYY(data) && isDateCCYYMMDD(data) {
+			warnings = append(warnings, newWarning(WarningIssuerDateFormat, element, "Date is in CCYYMMDD format but issuer "+issuer.Name()+" uses MMDDCCYY"))
+		} else if !usDates && !isDateCCYYMMDD(data) && isDateMMDDCCYY(data) {
+			warnings = append(warnings, newWarning(WarningIssuerDateFormat, element, "Date is in MMDDCCYY format but issuer "+issuer.Name()+" uses CCYYMMDD"))
+		}
+	}
+
+	return
+}
+
+func isDateMMDDCCYY(data string) bool {
+	if len(data) < 8 {
+		return false
+	}
+
+	return isDate(data[4:8], data[:2], data[2:4])
+}
+
+func isDateCCYYMMDD(data string) bool {
+	if len(data) < 8 {
+		return false
+	}
+
+	return isDate(data[:4], data[4:6], data[6:8])
+}
+
+func isDate(year string, month string, day string) bool {
+
+	// An implausible year is more likely to be the wrong half of a date in
+	// the other format than a genuine one.
+
+	date := dateFromParts("", year, month, day)
+
+	return date.IsPresent() && isPlausibleYear(date.Year())
+}
@@ -0,0 +1,90 @@
+package dlidparser
+
+import (
+	"strconv"
+	"time"
+)
+
+type DateStatus int
+
+const (
+	DateStatusAbsent DateStatus = iota
+	DateStatusPresent
+	DateStatusInvalid
+)
+
+// Date is a date from the card.  It embeds time.Time so that it can be used
+// like one, but also records whether the element was there at all and, if it
+// was, whether it made any sense.  Absent and invalid dates have a zero time.
+// The raw text of the element is kept so that invalid dates can still be
+// shown to a human.
+type Date struct {
+	time.Time
+	status DateStatus
+	raw    string
+}
+
+// NewDate makes a present date from a time, for building licences by hand.
+func NewDate(t time.Time) Date {
+	return Date{Time: t, status: DateStatusPresent}
+}
+
+func invalidDate(raw string) Date {
+	return Date{status: DateStatusInvalid, raw: raw}
+}
+
+func (d Date) Status() DateStatus {
+	return d.status
+}
+
+func (d Date) IsPresent() bool {
+	return d.status == DateStatusPresent
+}
+
+func (d Date) IsAbsent() bool {
+	return d.status == DateStatusAbsent
+}
+
+func (d Date) IsInvalid() bool {
+	return d.status == DateStatusInvalid
+}
+
+func (d Date) Raw() string {
+	return d.raw
+}
+
+// dateFromParts builds a date from the year, month and day digits that the
+// version-specific parsers have cut out of the raw element.  Anything that
+// isn't a real calendar date, such as the 31st of April, is invalid.
+func dateFromParts(raw string, year string, month string, day string) Date {
+
+	y, err := strconv.Atoi(year)
+
+	if err != nil || y < 1 {
+		return invalidDate(raw)
+	}
+
+	m, err := strconv.Atoi(month)
+
+	if err != nil || m < 1 || m > 12 {
+		return invalidDate(raw)
+	}
+
+	d, err := strconv.Atoi(day)
+
+	if err != nil || d < 1 || d > daysInMonth(y, time.Month(m)) {
+		return invalidDate(raw)
+	}
+
+	return Date{
+		Time:   time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC),
+		status: DateStatusPresent,
+		raw:    raw,
+	}
+}
+
+func daysInMonth(year int, month time.Month) int {
+
+	// Day zero of the next month is the last day of this one.
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,202 @@
+package dlidparser
+
+import (
+	"time"
+)
+
+type DateFormat int
+
+const (
+	DateFormatNone DateFormat = iota
+	DateFormatMMDDCCYY
+	DateFormatCCYYMMDD
+)
+
+// DateFormatRule records why the parser chose the date format that it did.
+type DateFormatRule int
+
+const (
+
+	// DateFormatRuleNone means that no format could be made to fit the dates.
+	DateFormatRuleNone DateFormatRule = iota
+
+	// DateFormatRuleVersion means that the version of the standard only
+	// allows one format.
+	DateFormatRuleVersion
+
+	// DateFormatRuleCountry means that the format was chosen from the DCG
+	// country element, as the standard intends.
+	DateFormatRuleCountry
+
+	// DateFormatRuleIssuer means that the country was missing or didn't fit
+	// the dates, so the format was chosen from the issuer's country instead.
+	DateFormatRuleIssuer
+
+	// DateFormatRuleUnambiguous means that neither country helped, but only
+	// one format produced valid, plausible dates in a sensible order.
+	DateFormatRuleUnambiguous
+)
+
+// Dates that fall outside this range are assumed to have been read in the
+// wrong format.
+const (
+	earliestPlausibleYear = 1880
+	latestPlausibleYear   = 2199
+)
+
+// licenceDates holds the raw date of birth, issue and expiry elements from a
+// version 3 or later licence, so that their format can be worked out before
+// any dates are parsed.  Only these three are used: they're mandatory, so
+// every card has them, whereas the optional dates are where jurisdictions put
+// their junk.
+
+type licenceDates struct {
+	dateOfBirth string
+	issueDate   string
+	expiryDate  string
+}
+
+func (l licenceDates) all() []string {
+	return []string{l.dateOfBirth, l.issueDate, l.expiryDate}
+}
+
+func (l licenceDates) isEmpty() bool {
+	return len(l.dateOfBirth) == 0 && len(l.issueDate) == 0 && len(l.expiryDate) == 0
+}
+
+func isPlausibleYear(year int) bool {
+	return year >= earliestPlausibleYear && year <= latestPlausibleYear
+}
+
+func countryDateFormat(country string) DateFormat {
+	switch country {
+	case "":
+		return DateFormatNone
+	case "USA":
+		return DateFormatMMDDCCYY
+	}
+
+	return DateFormatCCYYMMDD
+}
+
+// resolveDateFormat picks the format of the dates in a version 3 or later
+// licence.  Version 3 made the format depend on the country, which is fine
+// until a jurisdiction leaves the country out or gets it wrong, which they do
+// every week.  The country is trusted if the dates make sense in its format;
+// failing that the issuer's country is tried, and failing that whichever
+// format works.  Whether the dates are in a sensible order doesn't come into
+// it: a date can't be plausible in both formats, so the order could only ever
+// reject the one format that fits.  resolveDates reports it instead.
+func resolveDateFormat(dates licenceDates, country string, issuer string) (DateFormat, DateFormatRule) {
+
+	fits := func(format DateFormat) bool {
+		return format != DateFormatNone && datesFit(dates, format)
+	}
+
+	if format := countryDateFormat(country); fits(format) {
+		return format, DateFormatRuleCountry
+	}
+
+	if issuerDetails, ok := LookupIssuer(issuer); ok {
+		if format := countryDateFormat(issuerDetails.Country()); fits(format) {
+			return format, DateFormatRuleIssuer
+		}
+	}
+
+	// A date can't be plausible in both formats: read the other way round, the
+	// year of a US date would start with its month.  Only one format can fit.
+
+	if fits(DateFormatMMDDCCYY) {
+		return DateFormatMMDDCCYY, DateFormatRuleUnambiguous
+	}
+
+	if fits(DateFormatCCYYMMDD) {
+		return DateFormatCCYYMMDD, DateFormatRuleUnambiguous
+	}
+
+	// Nothing fits.  Go with the country if there is one; the dates will be
+	// marked invalid.
+	return countryDateFormat(country), DateFormatRuleNone
+}
+
+// datesFit checks that the dates are real, plausible dates in the given
+// format.  A date that isn't a date in either format tells us nothing about
+// which format the others are in, so it is skipped; it will be marked invalid
+// whichever format is chosen.  At least one date has to fit.
+func datesFit(dates licenceDates, format DateFormat) bool {
+
+	found := false
+
+	for _, data := range dates.all() {
+		if len(data) == 0 {
+			continue
+		}
+
+		if parseDateV3(data, DateFormatMMDDCCYY).IsInvalid() && parseDateV3(data, DateFormatCCYYMMDD).IsInvalid() {
+			continue
+		}
+
+		date := parseDateV3(data, format)
+
+		if !date.IsPresent() || !isPlausibleYear(date.Year()) {
+			return false
+		}
+
+		found = true
+	}
+
+	return found
+}
+
+// datesOrdered checks that the licencee was born in the past, before the card
+// was issued, and that the card was issued before it expires.  Real cards
+// always manage this, so it's a good sign that something is wrong.
+func datesOrdered(dates licenceDates, format DateFormat, now time.Time) bool {
+
+	dateOfBirth := parseDateV3(dates.dateOfBirth, format)
+	issueDate := parseDateV3(dates.issueDate, format)
+	expiryDate := parseDateV3(dates.expiryDate, format)
+
+	if dateOfBirth.IsPresent() && dateOfBirth.After(now) {
+		return false
+	}
+
+	if dateOfBirth.IsPresent() && issueDate.IsPresent() && !dateOfBirth.Before(issueDate.Time) {
+		return false
+	}
+
+	if issueDate.IsPresent() && expiryDate.IsPresent() && !issueDate.Before(expiryDate.Time) {
+		return false
+	}
+
+	return true
+}
+
+// resolveDates works out the format of the licence's dates and records it.
+// Anything other than the country deciding the format is a deviation from
+// the standard, so it is reported as a warning, as are dates that are in the
+// wrong order.  The order is only reported; it never changes the format.
+// Strict parsing uses only the country.
+func (d *DLIDLicense) resolveDates(dates licenceDates, issuer string, options ParseOptions) DateFormat {
+
+	d.dateFormat, d.dateFormatRule = resolveDateFormat(dates, d.Country(), issuer)
+
+	// Strict parsing takes the standard at its word: the country decides,
+	// and if the dates don't fit they're malformed.
+	if options.Mode == ParseModeStrict && d.dateFormatRule != DateFormatRuleCountry {
+		d.dateFormat, d.dateFormatRule = countryDateFormat(d.Country()), DateFormatRuleNone
+	}
+
+	switch {
+	case d.dateFormatRule == DateFormatRuleIssuer, d.dateFormatRule == DateFormatRuleUnambiguous:
+		d.addWarning(WarningDateFormat, "DCG", "Date format could not be taken from the country")
+	case d.dateFormatRule == DateFormatRuleNone && !dates.isEmpty():
+		d.addWarning(WarningDateFormat, "DCG", "Dates do not make sense in any format")
+	}
+
+	if d.dateFormat != DateFormatNone && !datesOrdered(dates, d.dateFormat, time.Now()) {
+		d.addWarning(WarningDateOrder, "", "Dates of birth, issue and expiry are out of order")
+	}
+
+	return d.dateFormat
+}
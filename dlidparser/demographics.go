@@ -0,0 +1,43 @@
+package dlidparser
+
+import (
+	"strings"
+)
+
+// These are the ANSI D-20 race and ethnicity codes.  Hispanic origin is coded
+// separately from the other categories, even though the field only holds one
+// value.
+type Race int
+
+const (
+	RaceNone Race = iota
+	RaceAlaskanOrAmericanIndian
+	RaceAsianOrPacificIslander
+	RaceBlack
+	RaceHispanicOrigin
+	RaceNonHispanic
+	RaceWhite
+	RaceUnknown
+)
+
+var raceCodes = map[string]Race{
+	"AI": RaceAlaskanOrAmericanIndian,
+	"AP": RaceAsianOrPacificIslander,
+	"BK": RaceBlack,
+	"H":  RaceHispanicOrigin,
+	"O":  RaceNonHispanic,
+	"W":  RaceWhite,
+	"U":  RaceUnknown,
+}
+
+func parseRace(data string) Race {
+	if race, ok := raceCodes[strings.ToUpper(data)]; ok {
+		return race
+	}
+
+	if len(data) > 0 {
+		return RaceUnknown
+	}
+
+	return RaceNone
+}
@@ -1,15 +1,31 @@
 package dlidparser
 
-import (
-	"time"
-)
-
 type DriverSex int
 
 const (
 	DriverSexNone DriverSex = iota
 	DriverSexMale
 	DriverSexFemale
+	DriverSexNotSpecified
+)
+
+type DocumentType int
+
+const (
+	DocumentTypeNone DocumentType = iota
+	DocumentTypeDriverLicense
+	DocumentTypeIdentificationCard
+	DocumentTypeDriverLicenseAndIdentificationCard
+)
+
+type ComplianceType int
+
+const (
+	ComplianceTypeNone ComplianceType = iota
+	ComplianceTypeFullyCompliant
+	ComplianceTypeMateriallyCompliant
+	ComplianceTypeNonCompliant
+	ComplianceTypeUnknown
 )
 
 type DLIDLicense struct {
@@ -17,23 +33,55 @@ type DLIDLicense struct {
 	middleNames           []string
 	lastName              string
 	nameSuffix            string
-	street                string
-	city                  string
-	state                 string
+	namePrefix            string
+	mailingAddress        Address
+	residenceAddress      Address
 	country               string
-	postal                string
 	sex                   DriverSex
 	socialSecurityNumber  string
-	dateOfBirth           time.Time
+	dateOfBirth           Date
 	issuerId              string
 	issuerName            string
-	expiryDate            time.Time
-	issueDate             time.Time
+	expiryDate            Date
+	issueDate             Date
 	vehicleClass          string
 	restrictionCodes      string
 	endorsementCodes      string
+	standardVehicleClass  string
+	standardRestrictions  string
+	standardEndorsements  string
 	customerId            string
 	documentDiscriminator string
+	inventoryControl      string
+	auditInformation      string
+	documentType          DocumentType
+	complianceType        ComplianceType
+	limitedDuration       bool
+	cardRevisionDate      Date
+	hazmatExpiryDate      Date
+	organDonor            bool
+	veteran               bool
+	under18Until          Date
+	under19Until          Date
+	under21Until          Date
+	dateFormat            DateFormat
+	dateFormatRule        DateFormatRule
+	subfiles              []*Subfile
+	jurisdictionSubfiles  []*JurisdictionSubfile
+	warnings              []Warning
+	elements              *Elements
+	height                Height
+	weight                Weight
+	weightRange           WeightRange
+	eyeColour             EyeColour
+	hairColour            HairColour
+	lastNameTruncation    Truncation
+	firstNameTruncation   Truncation
+	middleNameTruncation  Truncation
+	aliases               []Alias
+	placeOfBirth          string
+	race                  Race
+	federalVehicleCodes   string
 }
 
 func (d *DLIDLicense) SetFirstName(s string) {
@@ -68,28 +116,126 @@ func (d *DLIDLicense) NameSuffix() string {
 	return d.nameSuffix
 }
 
+func (d *DLIDLicense) SetNamePrefix(s string) {
+	d.namePrefix = s
+}
+
+func (d *DLIDLicense) NamePrefix() string {
+	return d.namePrefix
+}
+
+func (d *DLIDLicense) SetLastNameTruncation(t Truncation) {
+	d.lastNameTruncation = t
+}
+
+func (d *DLIDLicense) LastNameTruncation() Truncation {
+	return d.lastNameTruncation
+}
+
+func (d *DLIDLicense) SetFirstNameTruncation(t Truncation) {
+	d.firstNameTruncation = t
+}
+
+func (d *DLIDLicense) FirstNameTruncation() Truncation {
+	return d.firstNameTruncation
+}
+
+func (d *DLIDLicense) SetMiddleNameTruncation(t Truncation) {
+	d.middleNameTruncation = t
+}
+
+func (d *DLIDLicense) MiddleNameTruncation() Truncation {
+	return d.middleNameTruncation
+}
+
+func (d *DLIDLicense) SetAliases(a []Alias) {
+	d.aliases = a
+}
+
+func (d *DLIDLicense) Aliases() []Alias {
+	return d.aliases
+}
+
+func (d *DLIDLicense) SetPlaceOfBirth(s string) {
+	d.placeOfBirth = s
+}
+
+// PlaceOfBirth is free text; jurisdictions write whatever they like in it,
+// from a country code to a city and state.
+func (d *DLIDLicense) PlaceOfBirth() string {
+	return d.placeOfBirth
+}
+
+func (d *DLIDLicense) SetRace(r Race) {
+	d.race = r
+}
+
+func (d *DLIDLicense) Race() Race {
+	return d.race
+}
+
+func (d *DLIDLicense) SetFederalVehicleCodes(s string) {
+	d.federalVehicleCodes = s
+}
+
+// FederalVehicleCodes are the federally established vehicle class,
+// restriction and endorsement codes for commercial vehicles, as opposed to
+// the jurisdiction-specific codes in VehicleClass and friends.
+func (d *DLIDLicense) FederalVehicleCodes() string {
+	return d.federalVehicleCodes
+}
+
+// FederalVehicleClasses decodes FederalVehicleCodes using the standard vehicle
+// class table.  The standard allows restriction and endorsement codes in the
+// same element but doesn't say how to tell them apart, and the letters
+// overlap.  In practice jurisdictions only put the commercial class there, so
+// that's how it is read.
+func (d *DLIDLicense) FederalVehicleClasses() []VehicleCode {
+	return DecodeVehicleCodes(d.federalVehicleCodes, VehicleCodeTypeClass, "")
+}
+
+func (d *DLIDLicense) SetMailingAddress(a Address) {
+	d.mailingAddress = a
+}
+
+// MailingAddress is the address that the jurisdiction sends post to.  The
+// Street, City, State and Postal methods are shortcuts to its fields.
+func (d *DLIDLicense) MailingAddress() Address {
+	return d.mailingAddress
+}
+
+func (d *DLIDLicense) SetResidenceAddress(a Address) {
+	d.residenceAddress = a
+}
+
+// ResidenceAddress is where the licencee actually lives.  Cards only include
+// it when it differs from the mailing address, so it is usually empty.
+func (d *DLIDLicense) ResidenceAddress() Address {
+	return d.residenceAddress
+}
+
 func (d *DLIDLicense) SetStreet(s string) {
-	d.street = s
+	d.mailingAddress.SetStreet(s)
 }
 
 func (d *DLIDLicense) Street() string {
-	return d.street
+	return d.mailingAddress.Street()
 }
 
 func (d *DLIDLicense) SetCity(s string) {
-	d.city = s
+	d.mailingAddress.SetCity(s)
 }
 
 func (d *DLIDLicense) City() string {
-	return d.city
+	return d.mailingAddress.City()
 }
 
 func (d *DLIDLicense) SetState(s string) {
-	d.state = s
+	d.mailingAddress.SetState(s)
 }
 
 func (d *DLIDLicense) State() string {
-	return d.state
+	return d.mailingAddress.State()
 }
 
 func (d *DLIDLicense) SetCountry(s string) {
@@ -101,11 +247,15 @@ func (d *DLIDLicense) Country() string {
 }
 
 func (d *DLIDLicense) SetPostal(s string) {
-	d.postal = s
+	d.mailingAddress.SetPostal(s)
 }
 
 func (d *DLIDLicense) Postal() string {
-	return d.postal
+	return d.mailingAddress.Postal()
+}
+
+func (d *DLIDLicense) PostalCode() PostalCode {
+	return d.mailingAddress.PostalCode()
 }
 
 func (d *DLIDLicense) SetSex(s DriverSex) {
@@ -124,11 +274,11 @@ func (d *DLIDLicense) SocialSecurityNumber() string {
 	return d.socialSecurityNumber
 }
 
-func (d *DLIDLicense) SetDateOfBirth(t time.Time) {
+func (d *DLIDLicense) SetDateOfBirth(t Date) {
 	d.dateOfBirth = t
 }
 
-func (d *DLIDLicense) DateOfBirth() time.Time {
+func (d *DLIDLicense) DateOfBirth() Date {
 	return d.dateOfBirth
 }
 
@@ -148,6 +298,11 @@ func (d *DLIDLicense) SetIssuerName(s string) {
 	d.issuerName = s
 }
 
+// Issuer looks up the full details of the issuer in the registry.
+func (d *DLIDLicense) Issuer() (Issuer, bool) {
+	return LookupIssuer(d.issuerId)
+}
+
 func (d *DLIDLicense) VehicleClass() string {
 	return d.vehicleClass
 }
@@ -172,6 +327,59 @@ func (d *DLIDLicense) SetEndorsementCodes(s string) {
 	d.endorsementCodes = s
 }
 
+// VehicleClasses decodes the jurisdiction-specific vehicle class, using any
+// codes registered for the issuer before falling back to the standard table.
+func (d *DLIDLicense) VehicleClasses() []VehicleCode {
+	return DecodeVehicleCodes(d.vehicleClass, VehicleCodeTypeClass, d.issuerId)
+}
+
+func (d *DLIDLicense) Restrictions() []VehicleCode {
+	return DecodeVehicleCodes(d.restrictionCodes, VehicleCodeTypeRestriction, d.issuerId)
+}
+
+func (d *DLIDLicense) Endorsements() []VehicleCode {
+	return DecodeVehicleCodes(d.endorsementCodes, VehicleCodeTypeEndorsement, d.issuerId)
+}
+
+// The standard codes are the jurisdiction's codes translated into the ones
+// defined by AAMVA, so they are always decoded using the standard tables.
+
+func (d *DLIDLicense) StandardVehicleClass() string {
+	return d.standardVehicleClass
+}
+
+func (d *DLIDLicense) SetStandardVehicleClass(s string) {
+	d.standardVehicleClass = s
+}
+
+func (d *DLIDLicense) StandardRestrictionCodes() string {
+	return d.standardRestrictions
+}
+
+func (d *DLIDLicense) SetStandardRestrictionCodes(s string) {
+	d.standardRestrictions = s
+}
+
+func (d *DLIDLicense) StandardEndorsementCodes() string {
+	return d.standardEndorsements
+}
+
+func (d *DLIDLicense) SetStandardEndorsementCodes(s string) {
+	d.standardEndorsements = s
+}
+
+func (d *DLIDLicense) StandardVehicleClasses() []VehicleCode {
+	return DecodeVehicleCodes(d.standardVehicleClass, VehicleCodeTypeClass, "")
+}
+
+func (d *DLIDLicense) StandardRestrictions() []VehicleCode {
+	return DecodeVehicleCodes(d.standardRestrictions, VehicleCodeTypeRestriction, "")
+}
+
+func (d *DLIDLicense) StandardEndorsements() []VehicleCode {
+	return DecodeVehicleCodes(d.standardEndorsements, VehicleCodeTypeEndorsement, "")
+}
+
 func (d *DLIDLicense) CustomerId() string {
 	return d.customerId
 }
@@ -180,18 +388,274 @@ func (d *DLIDLicense) SetCustomerId(s string) {
 	d.customerId = s
 }
 
-func (d *DLIDLicense) SetExpiryDate(t time.Time) {
+// The document discriminator identifies this particular card, so it changes
+// every time the card is reissued even though the customer ID does not.
+func (d *DLIDLicense) DocumentDiscriminator() string {
+	return d.documentDiscriminator
+}
+
+func (d *DLIDLicense) SetDocumentDiscriminator(s string) {
+	d.documentDiscriminator = s
+}
+
+// The inventory control number is the serial number printed on the card stock.
+func (d *DLIDLicense) InventoryControl() string {
+	return d.inventoryControl
+}
+
+func (d *DLIDLicense) SetInventoryControl(s string) {
+	d.inventoryControl = s
+}
+
+// Audit information is a jurisdiction-defined record of when and where the
+// card was produced.
+func (d *DLIDLicense) AuditInformation() string {
+	return d.auditInformation
+}
+
+func (d *DLIDLicense) SetAuditInformation(s string) {
+	d.auditInformation = s
+}
+
+func (d *DLIDLicense) SetExpiryDate(t Date) {
 	d.expiryDate = t
 }
 
-func (d *DLIDLicense) ExpiryDate() time.Time {
+func (d *DLIDLicense) ExpiryDate() Date {
 	return d.expiryDate
 }
 
-func (d *DLIDLicense) SetIssueDate(t time.Time) {
+func (d *DLIDLicense) SetIssueDate(t Date) {
 	d.issueDate = t
 }
 
-func (d *DLIDLicense) IssueDate() time.Time {
+func (d *DLIDLicense) IssueDate() Date {
 	return d.issueDate
 }
+
+func (d *DLIDLicense) SetDocumentType(t DocumentType) {
+	d.documentType = t
+}
+
+func (d *DLIDLicense) DocumentType() DocumentType {
+	return d.documentType
+}
+
+func (d *DLIDLicense) SetComplianceType(c ComplianceType) {
+	d.complianceType = c
+}
+
+func (d *DLIDLicense) ComplianceType() ComplianceType {
+	return d.complianceType
+}
+
+func (d *DLIDLicense) SetLimitedDuration(b bool) {
+	d.limitedDuration = b
+}
+
+func (d *DLIDLicense) LimitedDuration() bool {
+	return d.limitedDuration
+}
+
+func (d *DLIDLicense) SetCardRevisionDate(t Date) {
+	d.cardRevisionDate = t
+}
+
+// CardRevisionDate is the date that the design of the card was last revised,
+// not the date that this particular card was issued.
+func (d *DLIDLicense) CardRevisionDate() Date {
+	return d.cardRevisionDate
+}
+
+func (d *DLIDLicense) SetHazmatExpiryDate(t Date) {
+	d.hazmatExpiryDate = t
+}
+
+func (d *DLIDLicense) HazmatExpiryDate() Date {
+	return d.hazmatExpiryDate
+}
+
+func (d *DLIDLicense) SetOrganDonor(b bool) {
+	d.organDonor = b
+}
+
+func (d *DLIDLicense) OrganDonor() bool {
+	return d.organDonor
+}
+
+func (d *DLIDLicense) SetVeteran(b bool) {
+	d.veteran = b
+}
+
+func (d *DLIDLicense) Veteran() bool {
+	return d.veteran
+}
+
+func (d *DLIDLicense) SetUnder18Until(t Date) {
+	d.under18Until = t
+}
+
+// Under18Until is the date that the licencee turns 18.  If the card doesn't
+// say, it is worked out from the date of birth.
+func (d *DLIDLicense) Under18Until() Date {
+	return d.ageUntil(d.under18Until, 18)
+}
+
+func (d *DLIDLicense) SetUnder19Until(t Date) {
+	d.under19Until = t
+}
+
+// Under19Until is the date that the licencee turns 19.  If the card doesn't
+// say, it is worked out from the date of birth.
+func (d *DLIDLicense) Under19Until() Date {
+	return d.ageUntil(d.under19Until, 19)
+}
+
+func (d *DLIDLicense) SetUnder21Until(t Date) {
+	d.under21Until = t
+}
+
+// Under21Until is the date that the licencee turns 21.  If the card doesn't
+// say, it is worked out from the date of birth.
+func (d *DLIDLicense) Under21Until() Date {
+	return d.ageUntil(d.under21Until, 21)
+}
+
+func (d *DLIDLicense) ageUntil(until Date, age int) Date {
+
+	if !until.IsAbsent() {
+		return until
+	}
+
+	if !d.dateOfBirth.IsPresent() {
+		return Date{}
+	}
+
+	// Anyone born on the 29th of February comes of age on the 1st of March
+	// in a non-leap year, which is exactly what AddDate does with the
+	// non-existent 29th.
+	return NewDate(d.dateOfBirth.AddDate(age, 0, 0))
+}
+
+// DateFormat is the format that the dates on the card were read in, and
+// DateFormatRule is the reason that format was chosen.
+func (d *DLIDLicense) DateFormat() DateFormat {
+	return d.dateFormat
+}
+
+func (d *DLIDLicense) DateFormatRule() DateFormatRule {
+	return d.dateFormatRule
+}
+
+func (d *DLIDLicense) SetHeight(h Height) {
+	d.height = h
+}
+
+func (d *DLIDLicense) Height() Height {
+	return d.height
+}
+
+func (d *DLIDLicense) SetWeight(w Weight) {
+	d.weight = w
+}
+
+func (d *DLIDLicense) Weight() Weight {
+	return d.weight
+}
+
+func (d *DLIDLicense) SetWeightRange(w WeightRange) {
+	d.weightRange = w
+}
+
+func (d *DLIDLicense) WeightRange() WeightRange {
+	return d.weightRange
+}
+
+func (d *DLIDLicense) SetEyeColour(c EyeColour) {
+	d.eyeColour = c
+}
+
+func (d *DLIDLicense) EyeColour() EyeColour {
+	return d.eyeColour
+}
+
+func (d *DLIDLicense) SetHairColour(c HairColour) {
+	d.hairColour = c
+}
+
+func (d *DLIDLicense) HairColour() HairColour {
+	return d.hairColour
+}
+
+func (d *DLIDLicense) SetSubfiles(s []*Subfile) {
+	d.subfiles = s
+}
+
+func (d *DLIDLicense) Subfiles() []*Subfile {
+	return d.subfiles
+}
+
+// Subfile returns the first subfile with the given designator, or nil if the
+// barcode did not contain one.
+func (d *DLIDLicense) Subfile(subfileType string) *Subfile {
+	for _, subfile := range d.subfiles {
+		if subfile.Type() == subfileType {
+			return subfile
+		}
+	}
+
+	return nil
+}
+
+func (d *DLIDLicense) SetJurisdictionSubfiles(s []*JurisdictionSubfile) {
+	d.jurisdictionSubfiles = s
+}
+
+func (d *DLIDLicense) JurisdictionSubfiles() []*JurisdictionSubfile {
+	return d.jurisdictionSubfiles
+}
+
+// JurisdictionSubfile returns the jurisdiction-specific subfile with the given
+// designator, or nil if the barcode did not contain one.
+func (d *DLIDLicense) JurisdictionSubfile(subfileType string) *JurisdictionSubfile {
+	for _, subfile := range d.jurisdictionSubfiles {
+		if subfile.Type() == subfileType {
+			return subfile
+		}
+	}
+
+	return nil
+}
+
+// Elements returns every data element in the licence subfile, in the order
+// they appeared, with their values exactly as they were stored.
+func (d *DLIDLicense) Elements() *Elements {
+	return d.elements
+}
+
+// Element returns the raw value of the given element, and whether it was
+// present.  The licence subfile is searched first, followed by every other
+// subfile in the order they appear in the directory.
+func (d *DLIDLicense) Element(id string) (value string, ok bool) {
+	if value, ok = d.elements.Value(id); ok {
+		return
+	}
+
+	for _, subfile := range d.subfiles {
+		if value, ok = subfile.Elements().Value(id); ok {
+			return
+		}
+	}
+
+	return
+}
+
+// Warnings returns the deviations from the standard that were worked around
+// while parsing, in the order that they were found.
+func (d *DLIDLicense) Warnings() []Warning {
+	return d.warnings
+}
+
+func (d *DLIDLicense) addWarning(code WarningCode, element string, message string) {
+	d.warnings = append(d.warnings, newWarning(code, element, message))
+}
@@ -1,7 +1,14 @@
 package dlidparser
 
 import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestBadHeader(t *testing.T) {
@@ -15,7 +22,7 @@ func TestBadHeader(t *testing.T) {
 }
 
 func TestIllegalVersion(t *testing.T) {
-	_, err := Parse("@\n\x1e\rANSI 636000080002")
+	_, err := Parse("@\n\x1e\rANSI 636000110002")
 
 	if err == nil {
 		t.Error("Illegal version not detected")
@@ -122,6 +129,11 @@ func TestV1ColoradoParser(t *testing.T) {
 	if s.LastName() != "PUBLIC" {
 		t.Error("V1 Colorado parser extracted wrong last name")
 	}
+
+	// DBD is the last element before the segment terminator.
+	if value, _ := s.Element("DBD"); value != "19961201" || s.IssueDate().Raw() != "19961201" {
+		t.Error("V1 Colorado parser included the segment terminator in the last element")
+	}
 }
 
 func TestV1Parser(t *testing.T) {
@@ -163,7 +175,7 @@ func TestV1Parser(t *testing.T) {
 		t.Error("V1 parser got wrong state")
 	}
 
-	if s.Postal() != "123459999" {
+	if s.Postal() != "12345+9999" {
 		t.Error("V1 parser got wrong postal code")
 	}
 
@@ -330,6 +342,9 @@ func TestV3Parser(t *testing.T) {
 	if s.IssueDate().Year() != 2012 {
 		t.Error("V3 parser got wrong issue year")
 	}
+	if s.DocumentDiscriminator() != "11111111111111111111" {
+		t.Error("V3 parser got wrong document discriminator")
+	}
 }
 
 func TestV4Parser(t *testing.T) {
@@ -438,6 +453,23 @@ func TestV4Parser(t *testing.T) {
 	if s.IssueDate().Year() != 2008 {
 		t.Error("V4 parser got wrong issue year")
 	}
+	if s.DocumentDiscriminator() != "2424244747474786102204" {
+		t.Error("V4 parser got wrong document discriminator")
+	}
+
+	if s.InventoryControl() != "123456789" {
+		t.Error("V4 parser got wrong inventory control number")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCJVA0123456789\nDCSSAMPLE", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("V4 parser failed on audit information")
+	}
+
+	if s.AuditInformation() != "VA0123456789" {
+		t.Error("V4 parser got wrong audit information")
+	}
 }
 
 func TestV5Parser(t *testing.T) {
@@ -807,3 +839,1897 @@ func TestV7CanadaParser(t *testing.T) {
 		t.Error("V7 Canada parser got wrong issue year")
 	}
 }
+
+func TestSubfileDirectory(t *testing.T) {
+	s, err := Parse("@\n\x1e\rANSI 636000070002DL00410282ZV03190008DLDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB06071986\nDBA12102012\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAM\nDDB06062008\nDDC06062009\nDDD1\rZVZVA01\r")
+
+	if err != nil {
+		t.Error("Subfile directory parser failed")
+	}
+
+	if len(s.Subfiles()) != 2 {
+		t.Fatal("Subfile directory parser found wrong number of subfiles")
+	}
+
+	if s.Subfiles()[0].Type() != "DL" || s.Subfiles()[0].Offset() != 41 || s.Subfiles()[0].Length() != 282 {
+		t.Error("Subfile directory parser got wrong DL entry")
+	}
+
+	if s.Subfiles()[1].Type() != "ZV" || s.Subfiles()[1].Offset() != 319 || s.Subfiles()[1].Length() != 8 {
+		t.Error("Subfile directory parser got wrong ZV entry")
+	}
+
+	if s.Subfile("ZV").Data() != "ZVZVA01\r" {
+		t.Error("Subfile directory parser failed to locate ZV data")
+	}
+
+	if s.Subfile("ZZ") != nil {
+		t.Error("Subfile directory parser found a subfile that does not exist")
+	}
+}
+
+func TestV1SubfileDirectory(t *testing.T) {
+	s, err := Parse("@\n\x1e\rANSI 6360200102DL00390187ZV02260031DLDAQ0123456789ABC\nDAAJOHN,Q,PUBLIC\nDAG123 MAIN STREET\nDAIANYTOWN\nDAJVA\nDAK123459999  \nDARDM  \nDAS          \nDAT     \nDAU509\nDAW175\nDAYBL \nDAZBR \nDBA20011201\nDBB19761123\nDBCM\nDBD19961201\rZVZVAJURISDICTIONDEFINEDELEMENT\r")
+
+	if err != nil {
+		t.Error("V1 subfile directory parser failed")
+	}
+
+	if len(s.Subfiles()) != 2 {
+		t.Fatal("V1 subfile directory parser found wrong number of subfiles")
+	}
+
+	if s.Subfile("ZV").Data() != "ZVZVAJURISDICTIONDEFINEDELEMENT" {
+		t.Error("V1 subfile directory parser got wrong ZV data")
+	}
+}
+
+func TestJurisdictionSubfile(t *testing.T) {
+	s, err := Parse("@\n\x1e\rANSI 636015030002DL00410217ZT02020022DLDCAB\nDCBLP\nDCDP\nDBA04052018\nDCSJONES\nDCTJAMES ROBERT R\nDBD07082012\nDBB10111978\nDBC1\nDAYBRO\nDAU 70 IN\nDAG123 SOME STREET\nDAICITY 12\nDAJTX\nDAK902100000  \nDAQ22334455\nDCF11111111111111111111\nDCGUSA\nDCHB   \nDAZBRO\nDCU\rZTZTA220\nZTBW\n")
+
+	if err != nil {
+		t.Error("Jurisdiction subfile parser failed")
+	}
+
+	z := s.JurisdictionSubfile("ZT")
+
+	if z == nil {
+		t.Fatal("Jurisdiction subfile parser did not find ZT subfile")
+	}
+
+	if len(z.Elements()) != 2 {
+		t.Error("Jurisdiction subfile parser found wrong number of elements")
+	}
+
+	if z.Element("ZTA") != "220" {
+		t.Error("Jurisdiction subfile parser got wrong ZTA element")
+	}
+
+	if z.Element("ZTB") != "W" {
+		t.Error("Jurisdiction subfile parser got wrong ZTB element")
+	}
+}
+
+func TestJurisdictionDecoder(t *testing.T) {
+	defer RegisterJurisdictionDecoder("636000", nil)
+
+	RegisterJurisdictionDecoder("636000", func(elements map[string]string) map[string]string {
+		return map[string]string{"example": elements["ZVA"]}
+	})
+
+	s, err := Parse("@\n\x1e\rANSI 636000070002DL00410282ZV03190008DLDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB06071986\nDBA12102012\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAM\nDDB06062008\nDDC06062009\nDDD1\rZVZVA01\r")
+
+	if err != nil {
+		t.Error("Jurisdiction decoder parser failed")
+	}
+
+	z := s.JurisdictionSubfile("ZV")
+
+	if z == nil {
+		t.Fatal("Jurisdiction decoder parser did not find ZV subfile")
+	}
+
+	if z.Element("ZVA") != "01" {
+		t.Error("Jurisdiction decoder parser got wrong ZVA element")
+	}
+
+	if z.Field("example") != "01" {
+		t.Error("Jurisdiction decoder was not applied")
+	}
+}
+
+func TestJurisdictionDecoderRegistry(t *testing.T) {
+	texas := "@\n\x1e\rANSI 636015030002DL00410217ZT02020022DLDCAB\nDCBLP\nDCDP\nDBA04052018\nDCSJONES\nDCTJAMES ROBERT R\nDBD07082012\nDBB10111978\nDBC1\nDAYBRO\nDAU 70 IN\nDAG123 SOME STREET\nDAICITY 12\nDAJTX\nDAK902100000  \nDAQ22334455\nDCF11111111111111111111\nDCGUSA\nDCHB   \nDAZBRO\nDCU\rZTZTA220\nZTBW\n"
+
+	s, err := Parse(texas)
+
+	if err != nil {
+		t.Fatal("Jurisdiction registry parser failed")
+	}
+
+	if z := s.JurisdictionSubfile("ZT"); len(z.Fields()) != 0 || z.Element("ZTA") != "220" {
+		t.Error("Jurisdiction registry has a built-in decoder")
+	}
+
+	// A decoder for Texas, as a caller who knows what the elements mean
+	// would write one.  The field names are placeholders.
+	RegisterJurisdictionDecoder("636015", func(elements map[string]string) map[string]string {
+		return map[string]string{"first": elements["ZTA"], "second": elements["ZTB"]}
+	})
+
+	defer RegisterJurisdictionDecoder("636015", nil)
+
+	s, err = Parse(texas)
+
+	if err != nil {
+		t.Fatal("Jurisdiction registry parser failed")
+	}
+
+	if z := s.JurisdictionSubfile("ZT"); z.Field("first") != "220" || z.Field("second") != "W" {
+		t.Error("Jurisdiction registry did not apply a registered decoder")
+	}
+}
+
+func TestIdentificationCardParser(t *testing.T) {
+	s, err := Parse("@\n\x1e\rANSI 636000070002ID00410282ZV03190008IDDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB06071986\nDBA12102012\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAM\nDDB06062008\nDDC06062009\nDDD1\rZVZVA01\r")
+
+	if err != nil {
+		t.Fatal("ID card parser failed")
+	}
+
+	if s.DocumentType() != DocumentTypeIdentificationCard {
+		t.Error("ID card parser got wrong document type")
+	}
+
+	if s.FirstName() != "MICHAEL" {
+		t.Error("ID card parser extracted wrong first name")
+	}
+
+	if s.LastName() != "SAMPLE" {
+		t.Error("ID card parser extracted wrong last name")
+	}
+
+	if s.Street() != "2300 WEST BROAD STREET" {
+		t.Error("ID card parser got wrong street")
+	}
+
+	if s.DateOfBirth().Year() != 1986 {
+		t.Error("ID card parser got wrong date of birth year")
+	}
+}
+
+func TestDocumentType(t *testing.T) {
+	s, err := Parse("@\n\x1e\rANSI 636000070002DL00410282ZV03190008DLDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB06071986\nDBA12102012\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAM\nDDB06062008\nDDC06062009\nDDD1\rZVZVA01\r")
+
+	if err != nil {
+		t.Fatal("Document type parser failed")
+	}
+
+	if s.DocumentType() != DocumentTypeDriverLicense {
+		t.Error("Document type parser got wrong document type for licence")
+	}
+
+	s, err = Parse("@\n\x1e\rANSI 636000070002DL00410079ID01200079DLDAQT64235789\nDCSSAMPLE\nDACMICHAEL\nDBB06071986\nDBA12102012\nDBD06062008\nDCGUSA\rIDDAQT64235789\nDCSSAMPLE\nDACMICHAEL\nDBB06071986\nDBA12102012\nDBD06062008\nDCGUSA\r")
+
+	if err != nil {
+		t.Fatal("Document type parser failed on combined card")
+	}
+
+	if s.DocumentType() != DocumentTypeDriverLicenseAndIdentificationCard {
+		t.Error("Document type parser got wrong document type for combined card")
+	}
+
+	if s.Subfile("ID").Data() != "IDDAQT64235789\nDCSSAMPLE\nDACMICHAEL\nDBB06071986\nDBA12102012\nDBD06062008\nDCGUSA\r" {
+		t.Error("Document type parser got wrong ID subfile data")
+	}
+}
+
+func TestV8Parser(t *testing.T) {
+	s, err := Parse("@\n\x1e\rANSI 636000080002DL00410282ZV03190008DLDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB06071986\nDBA12102012\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAF\nDDB06062008\nDDC06062009\nDDD1\rZVZVA01\r")
+
+	if err != nil {
+		t.Fatal("V8 parser failed")
+	}
+
+	if s.IssuerName() != "Virginia" {
+		t.Error("V8 parser extracted wrong issuer")
+	}
+
+	if s.FirstName() != "MICHAEL" {
+		t.Error("V8 parser extracted wrong first name")
+	}
+
+	if s.LastName() != "SAMPLE" {
+		t.Error("V8 parser extracted wrong last name")
+	}
+
+	if s.Postal() != "23269" {
+		t.Error("V8 parser got wrong postal code")
+	}
+
+	if s.Sex() != DriverSexMale {
+		t.Error("V8 parser got wrong sex")
+	}
+
+	if s.DateOfBirth().Day() != 7 || s.DateOfBirth().Month() != 6 || s.DateOfBirth().Year() != 1986 {
+		t.Error("V8 parser got wrong date of birth")
+	}
+
+	if s.ComplianceType() != ComplianceTypeFullyCompliant {
+		t.Error("V8 parser got wrong compliance type")
+	}
+
+	if !s.LimitedDuration() {
+		t.Error("V8 parser got wrong limited duration indicator")
+	}
+}
+
+func TestV10Parser(t *testing.T) {
+	s, err := Parse("@\n\x1e\rANSI 636000100002DL00410282ZV03190008DLDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB06071986\nDBA12102012\nDBC9\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAN\nDDB06062008\nDDC06062009\nDDD0\rZVZVA01\r")
+
+	if err != nil {
+		t.Fatal("V10 parser failed")
+	}
+
+	if s.Sex() != DriverSexNotSpecified {
+		t.Error("V10 parser got wrong sex")
+	}
+
+	if s.ComplianceType() != ComplianceTypeNonCompliant {
+		t.Error("V10 parser got wrong compliance type")
+	}
+
+	if s.LimitedDuration() {
+		t.Error("V10 parser got wrong limited duration indicator")
+	}
+
+	if s.ExpiryDate().Year() != 2012 {
+		t.Error("V10 parser got wrong expiry year")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDBC9", "636000", 7, ParseOptions{})
+
+	if err != nil || s.Sex() != DriverSexNone {
+		t.Error("V7 parser accepted sex code 9")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDBC9", "636000", 9, ParseOptions{})
+
+	if err != nil || s.Sex() != DriverSexNone {
+		t.Error("V9 parser accepted sex code 9")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse("@\n\x1e\rANSI 636000110002")
+
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Error("Unsupported version error has wrong kind")
+	}
+
+	var parseError *ParseError
+
+	if !errors.As(err, &parseError) {
+		t.Fatal("Unsupported version error is not a ParseError")
+	}
+
+	if parseError.Version() != 11 || parseError.Issuer() != "636000" || parseError.Offset() != 15 {
+		t.Error("Unsupported version error has wrong details")
+	}
+
+	_, err = Parse("@\n\x1d\rANSI 636")
+
+	if !errors.Is(err, ErrHeader) {
+		t.Error("Bad header error has wrong kind")
+	}
+
+	_, err = Parse("@\n\x1e\rANSI 636000070001DL00310010DLDAQT6423")
+
+	if !errors.Is(err, ErrSubfileRange) {
+		t.Error("Bad subfile range error has wrong kind")
+	}
+
+	if !errors.As(err, &parseError) || parseError.Offset() != 31 {
+		t.Error("Bad subfile range error has wrong offset")
+	}
+
+	_, err = Parse("@\n\x1e\rANSI 636000070002DL00410282ZV03190008XXDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB06071986\nDBA12102012\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAM\nDDB06062008\nDDC06062009\nDDD1\rZVZVA01\r")
+
+	if !errors.Is(err, ErrSubfileRange) {
+		t.Error("Missing subfile header error has wrong kind")
+	}
+
+	if !errors.As(err, &parseError) || parseError.Offset() != 41 || parseError.Version() != 7 {
+		t.Error("Missing subfile header error has wrong details")
+	}
+}
+
+func TestTruncatedData(t *testing.T) {
+	_, err := Parse("@\n\x1e\rANSI 63600007")
+
+	if !errors.Is(err, ErrSubfileRange) {
+		t.Error("Truncated header did not cause a subfile range error")
+	}
+
+	_, err = Parse("@\n\x1e\rANSI 636000070002DL-0410282")
+
+	if !errors.Is(err, ErrSubfileRange) {
+		t.Error("Negative payload location did not cause a subfile range error")
+	}
+
+	s, err := Parse("@\n\x1e\rANSI 636000070001DL00310036DLDCSSAMPLE\nDBB\nDBA1\nDAK2326\nDCGUSA\r\r")
+
+	if err != nil {
+		t.Fatal("Truncated elements caused an error")
+	}
+
+	if s.LastName() != "SAMPLE" {
+		t.Error("Truncated elements parser extracted wrong last name")
+	}
+
+	if s.Postal() != "2326" {
+		t.Error("Truncated elements parser got wrong postal code")
+	}
+}
+
+func TestStrictParser(t *testing.T) {
+	strict := ParseOptions{Mode: ParseModeStrict}
+
+	_, err := ParseWithOptions("@\n\x1e\rANSI 636000070002DL00410281ZV03220008DLDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB06071986\nDBA12102012\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAM\nDDB06062008\nDDC06062009\nDDD1\rZVZVA01\r", strict)
+
+	if err != nil {
+		t.Error("Strict parser rejected valid data")
+	}
+
+	_, err = ParseWithOptions("@\n\x1c\rAAMVA6360250102DL00390185ZV02260031DAQ0123456789ABC\nDAAPUBLIC,JOHN,Q\nDAG123 MAIN STREET\nDAIANYTOWN\nDAJVA\nDAK123459999  \nDARDM  \nDAS          \nDAT     \nDAU509\nDAW175\nDAYBL \nDAZBR \nDBA20011201\nDBB19761123\nDBCM\nDBD19961201\rZVZVAJURISDICTIONDEFINEDELEMENT\r", strict)
+
+	if !errors.Is(err, ErrHeader) {
+		t.Error("Strict parser accepted wrong separator")
+	}
+
+	_, err = ParseWithOptions("@\n\x1e\rANSI 6360060102DL00390185ZV02260031DAQ0123456789ABC\nDAAPUBLIC,JOHN,Q\nDAG123 MAIN STREET\nDAIANYTOWN\nDAJVA\nDAK123459999  \nDARDM  \nDAS          \nDAT     \nDAU509\nDAW175\nDAYBL \nDAZBR \nDBA20011201\nDBB19761123\nDBCM\nDBD19961201\rZVZVAJURISDICTIONDEFINEDELEMENT\r", strict)
+
+	if !errors.Is(err, ErrSubfileRange) {
+		t.Error("Strict parser accepted missing subfile header")
+	}
+
+	_, err = ParseWithOptions("@\n\x1e\rANSI 6360350101DL00290178DLDAACDL,SAMPLE,CARD\nDAQC34078360601\nDBA20120101\nDBB19600101\nHMK%>?84_MAD@I,GXHUEMBM,XCCBHUFE@HMG\"<:8$,,,4,,PM^MHM_^=>,4,,,4,HUXO\\GT&PNH>$<;<,=?PNOJHMY!<;PM[=!<HUUN@A", strict)
+
+	if !errors.Is(err, ErrSubfileRange) {
+		t.Error("Strict parser accepted overflowing Illinois range")
+	}
+
+	_, err = ParseWithOptions("@\n\x1e\rANSI 636000070001DL00310056DLDAQT64235789\nDCSSAMPLE\nDBB06071986\nDBA12102012\nDCGUSA\r\r", strict)
+
+	var parseError *ParseError
+
+	if !errors.Is(err, ErrMissingElement) || !errors.As(err, &parseError) || parseError.Element() != "DBD" {
+		t.Error("Strict parser accepted missing issue date")
+	}
+
+	malformedDate := "@\n\x1e\rANSI 636000070001DL00310062DLDAQT64235789\nDCSSAMPLE\nDBB06071986\nDBA12102012\nDBDXX\nDCGUSA\r\r"
+
+	_, err = ParseWithOptions(malformedDate, strict)
+
+	if !errors.Is(err, ErrMalformedDate) {
+		t.Error("Strict parser accepted malformed issue date")
+	}
+
+	if !errors.As(err, &parseError) || malformedDate[parseError.Offset():parseError.Offset()+3] != "DBD" {
+		t.Error("Strict parser reported wrong offset for malformed issue date")
+	}
+
+	_, err = ParseWithOptions("@\n\x1e\rANSI 636000070002DL00410281ZV03190008DLDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB06071986\nDBA12102012\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAM\nDDB06062008\nDDC06062009\nDDD1\rZVZVA01\r", strict)
+
+	if !errors.Is(err, ErrSubfileRange) {
+		t.Error("Strict parser accepted misplaced jurisdiction subfile")
+	}
+
+	_, err = ParseWithOptions("@\n\x1e\rANSI 6360000700XXDL00310056DLDAQT64235789\nDCSSAMPLE\nDBB06071986\nDBA12102012\nDCGUSA\r\r", strict)
+
+	if !errors.Is(err, ErrHeader) {
+		t.Error("Strict parser accepted malformed number of entries")
+	}
+
+	_, err = ParseWithOptions("@\n\x1e\rANSI 6360200101DL00290117DLDAQ0123456789ABC\nDAAJOHN Q PUBLIC\nDAL123 MAIN STREET\nDANANYTOWN\nDAOCO\nDAP80202\nDBA20011201\nDBB19761123\nDBD19961201\r", strict)
+
+	if !errors.Is(err, ErrMissingElement) || !errors.As(err, &parseError) || parseError.Element() != "DAG" {
+		t.Error("Strict parser used residence address in place of mailing address")
+	}
+
+	s, err := parseDataV1("DLDAQ0123456789ABC\nDAAJOHN Q PUBLIC\nDAL123 MAIN STREET\nDANANYTOWN\nDAOCO\nDAP80202", ColoradoIssuerId, strict)
+
+	if err != nil {
+		t.Fatal("Strict V1 parser failed")
+	}
+
+	if s.LastName() != "JOHN Q PUBLIC" || s.FirstName() != "" || hasWarning(s, WarningNameDelimiter, "DAA") {
+		t.Error("Strict V1 parser accepted space name delimiter")
+	}
+
+	if s.Street() != "" || s.City() != "" || hasWarning(s, WarningResidenceAddress, "DAL") {
+		t.Error("Strict V1 parser used residence address in place of mailing address")
+	}
+
+	s, err = parseDataV1("DLDAQ0123456789ABC\nDAAJOHN,Q,PUBLIC", ColoradoIssuerId, strict)
+
+	if err != nil || s.LastName() != "JOHN" || s.FirstName() != "Q" || hasWarning(s, WarningNameOrder, "DAA") {
+		t.Error("Strict V1 parser accepted Colorado name order")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDBD20080606\nDBB19860607\nDBA20121210\nDCGUSA", "636000", 4, strict)
+
+	if err != nil || s.DateFormatRule() != DateFormatRuleNone || !s.DateOfBirth().IsInvalid() {
+		t.Error("Strict parser ignored the country's date format")
+	}
+
+	_, err = parseDataV1("LDAQ0123456789ABC\nDAAPUBLIC,JOHN,Q", "636005", strict)
+
+	if !errors.Is(err, ErrSubfileRange) {
+		t.Error("Strict V1 parser accepted off-by-one offset")
+	}
+}
+
+func TestSubfileAtEndOfData(t *testing.T) {
+	s, err := Parse("@\n\x1e\rANSI 636000070001DL00310032DLDAQT64235789\nDCSSAMPLE\nDCGUSA\r")
+
+	if err != nil {
+		t.Fatal("End of data parser rejected terminated subfile at end of data")
+	}
+
+	if s.LastName() != "SAMPLE" || s.Country() != "USA" {
+		t.Error("End of data parser got wrong elements")
+	}
+
+	_, err = ParseWithOptions("@\n\x1e\rANSI 636000070001DL00310032DLDAQT64235789\nDCSSAMPLE\nDCGUSA\r", ParseOptions{Mode: ParseModeStrict})
+
+	if errors.Is(err, ErrSubfileRange) {
+		t.Error("Strict end of data parser rejected terminated subfile at end of data")
+	}
+
+	_, err = Parse("@\n\x1e\rANSI 636000070001DL00310032DLDAQT64235789\nDCSSAMPLE\nDCGUSAX")
+
+	if !errors.Is(err, ErrSubfileRange) {
+		t.Error("End of data parser accepted unterminated subfile at end of data")
+	}
+}
+
+func TestLenientParser(t *testing.T) {
+	lenient := ParseOptions{Mode: ParseModeLenient}
+
+	s, err := ParseWithOptions("@\n\x1e\rANSI 636000070001DL00310999DAQT64235789\nDCSSAMPLE\nDBB06071986\nDBA12102012\nDCGUSA", lenient)
+
+	if err != nil {
+		t.Fatal("Lenient parser failed")
+	}
+
+	if s.LastName() != "SAMPLE" {
+		t.Error("Lenient parser extracted wrong last name")
+	}
+
+	if s.DateOfBirth().Year() != 1986 {
+		t.Error("Lenient parser got wrong date of birth year")
+	}
+
+	_, err = Parse("@\n\x1e\rANSI 636000070001DL00310999DAQT64235789\nDCSSAMPLE\nDBB06071986\nDBA12102012\nDCGUSA")
+
+	if err == nil {
+		t.Error("Default parser accepted overflowing range")
+	}
+
+	_, err = ParseWithOptions("@\n\x1e\rANSI 636000110001DL00310032DLDAQT64235789\nDCSSAMPLE\nDCGUSA\r\r", lenient)
+
+	if err != nil {
+		t.Error("Lenient parser rejected newer version")
+	}
+}
+
+func hasWarning(s *DLIDLicense, code WarningCode, element string) bool {
+	for _, warning := range s.Warnings() {
+		if warning.Code() == code && warning.Element() == element {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestWarnings(t *testing.T) {
+	s, err := Parse("@\n\x1c\rAAMVA6360200102DL00390187ZV02260031DLDAQ0123456789ABC\nDAAJOHN Q PUBLIC\nDAL123 MAIN STREET\nDAIANYTOWN\nDAJVA\nDAK123459999  \nDARDM  \nDAS          \nDAT     \nDZZ509\nDAW175\nDAYBL \nDAZBR \nDBA20011201\nDBB19761123\nDBCM\nDBD19961201\rZVZVAJURISDICTIONDEFINEDELEMENT\r")
+
+	if err != nil {
+		t.Fatal("Warnings parser failed")
+	}
+
+	if !hasWarning(s, WarningSeparator, "") {
+		t.Error("Warnings parser did not report wrong separator")
+	}
+
+	if !hasWarning(s, WarningFileType, "") {
+		t.Error("Warnings parser did not report wrong file type")
+	}
+
+	if !hasWarning(s, WarningNameOrder, "DAA") {
+		t.Error("Warnings parser did not report Colorado name order")
+	}
+
+	if !hasWarning(s, WarningNameDelimiter, "DAA") {
+		t.Error("Warnings parser did not report Colorado name delimiter")
+	}
+
+	if hasWarning(s, WarningResidenceAddress, "DAL") || s.Street() != "" {
+		t.Error("Warnings parser substituted residence address into partial mailing address")
+	}
+
+	if !hasWarning(s, WarningUnknownElement, "DZZ") {
+		t.Error("Warnings parser did not report unknown element")
+	}
+
+	s, err = Parse("@\n\x1e\rANSI 6360350101DL00290178DLDAACDL,SAMPLE,CARD\nDAQC34078360601\nDBA20120101\nDBB19600101\nHMK%>?84_MAD@I,GXHUEMBM,XCCBHUFE@HMG\"<:8$,,,4,,PM^MHM_^=>,4,,,4,HUXO\\GT&PNH>$<;<,=?PNOJHMY!<;PM[=!<HUUN@A")
+
+	if err != nil {
+		t.Fatal("Warnings parser failed on Illinois data")
+	}
+
+	if !hasWarning(s, WarningSubfileRange, "") {
+		t.Error("Warnings parser did not report Illinois range")
+	}
+
+	s, err = Parse("@\n\x1e\rANSI 636000070002DL00410282ZV03190008DLDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB06071986\nDBA12102012\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAM\nDDB06062008\nDDC06062009\nDDD1\rZVZVA01\r")
+
+	if err != nil {
+		t.Fatal("Warnings parser failed on V7 data")
+	}
+
+	if !hasWarning(s, WarningSubfileOffset, "") {
+		t.Error("Warnings parser did not report misplaced subfile")
+	}
+
+	if hasWarning(s, WarningSeparator, "") {
+		t.Error("Warnings parser reported a separator problem that does not exist")
+	}
+}
+
+func TestRawElements(t *testing.T) {
+	s, err := Parse("@\n\x1e\rANSI 636000070002DL00410282ZV03190008DLDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB06071986\nDBA12102012\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAM\nDDB06062008\nDDC06062009\nDDD1\rZVZVA01\r")
+
+	if err != nil {
+		t.Fatal("Raw element parser failed")
+	}
+
+	if s.Elements().Len() != 28 {
+		t.Error("Raw element parser found wrong number of elements")
+	}
+
+	if s.Elements().IDs()[0] != "DAQ" || s.Elements().IDs()[27] != "DDD" {
+		t.Error("Raw element parser did not preserve element order")
+	}
+
+	if value, ok := s.Element("DAU"); !ok || value != "068 in" {
+		t.Error("Raw element parser got wrong DAU element")
+	}
+
+	if value, ok := s.Element("DAK"); !ok || value != "232690000 " {
+		t.Error("Raw element parser did not preserve padding")
+	}
+
+	if value, ok := s.Element("ZVA"); !ok || value != "01" {
+		t.Error("Raw element parser got wrong ZVA element")
+	}
+
+	if _, ok := s.Element("DAW"); ok {
+		t.Error("Raw element parser found an element that does not exist")
+	}
+
+	if s.Subfile("DL").Elements() != s.Elements() {
+		t.Error("Raw element parser did not attach elements to licence subfile")
+	}
+}
+
+func TestPhysicalDescriptors(t *testing.T) {
+	s, err := Parse("@\n\x1e\rANSI 6360000102DL00390187ZV02260031DLDAQ0123456789ABC\nDAAPUBLIC,JOHN,Q\nDAG123 MAIN STREET\nDAIANYTOWN\nDAJVA\nDAK123459999  \nDARDM  \nDAS          \nDAT     \nDAU509\nDAW175\nDAYBL \nDAZBR \nDBA20011201\nDBB19761123\nDBCM\nDBD19961201\rZVZVAJURISDICTIONDEFINEDELEMENT\r")
+
+	if err != nil {
+		t.Fatal("V1 physical descriptor parser failed")
+	}
+
+	if s.Height().Inches() != 69 || s.Height().Unit() != MeasurementUnitImperial {
+		t.Error("V1 parser got wrong height")
+	}
+
+	if s.Weight().Pounds() != 175 {
+		t.Error("V1 parser got wrong weight")
+	}
+
+	if s.EyeColour() != EyeColourBlue {
+		t.Error("V1 parser got wrong eye colour")
+	}
+
+	if s.HairColour() != HairColourBrown {
+		t.Error("V1 parser got wrong hair colour")
+	}
+
+	s, err = Parse("@\n\x1e\rANSI 636000070002DL00410282ZV03190008DLDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB06071986\nDBA12102012\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAM\nDDB06062008\nDDC06062009\nDDD1\rZVZVA01\r")
+
+	if err != nil {
+		t.Fatal("V7 physical descriptor parser failed")
+	}
+
+	if s.Height().Inches() != 68 || s.Height().Centimetres() != 173 {
+		t.Error("V7 parser got wrong height")
+	}
+
+	if s.EyeColour() != EyeColourBrown {
+		t.Error("V7 parser got wrong eye colour")
+	}
+
+	if !s.Weight().IsZero() || s.HairColour() != HairColourNone {
+		t.Error("V7 parser invented missing physical descriptors")
+	}
+
+	if h := parseHeight("180 cm", false); h.Unit() != MeasurementUnitMetric || h.Value() != 180 || h.Inches() != 71 {
+		t.Error("Height parser got wrong metric height")
+	}
+
+	if w := parseWeight("080", MeasurementUnitMetric); w.Kilograms() != 80 || w.Pounds() != 176 {
+		t.Error("Weight parser got wrong metric weight")
+	}
+
+	if min, max := parseWeightRange("3").Pounds(); min != 131 || max != 160 {
+		t.Error("Weight range parser got wrong range")
+	}
+
+	if parseWeightRange("X") != WeightRangeNone {
+		t.Error("Weight range parser accepted invalid range")
+	}
+}
+
+func TestNameTruncationAndAliases(t *testing.T) {
+	s, err := parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDDET\nDACMICHAEL\nDDFN\nDADJOHN\nDDGU\nDBNSMITH\nDBGMIKE\nDBSJR\nDBNJONES\nDBGMICK", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Name parser failed")
+	}
+
+	if s.LastNameTruncation() != TruncationTruncated {
+		t.Error("Name parser got wrong last name truncation")
+	}
+
+	if s.FirstNameTruncation() != TruncationNotTruncated {
+		t.Error("Name parser got wrong first name truncation")
+	}
+
+	if s.MiddleNameTruncation() != TruncationUnknown {
+		t.Error("Name parser got wrong middle name truncation")
+	}
+
+	if len(s.Aliases()) != 2 {
+		t.Fatal("Name parser found wrong number of aliases")
+	}
+
+	if s.Aliases()[0] != NewAlias("SMITH", "MIKE", "JR") {
+		t.Error("Name parser got wrong first alias")
+	}
+
+	if s.Aliases()[1] != NewAlias("JONES", "MICK", "") {
+		t.Error("Name parser got wrong second alias")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Name parser failed")
+	}
+
+	if s.LastNameTruncation() != TruncationNone || len(s.Aliases()) != 0 {
+		t.Error("Name parser invented missing name details")
+	}
+}
+
+func TestCardStatus(t *testing.T) {
+	s, err := Parse("@\n\x1e\rANSI 636000070002DL00410282ZV03190008DLDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB06071986\nDBA12102012\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAM\nDDB06062008\nDDC06062009\nDDD1\rZVZVA01\r")
+
+	if err != nil {
+		t.Fatal("Card status parser failed")
+	}
+
+	if s.ComplianceType() != ComplianceTypeMateriallyCompliant {
+		t.Error("Card status parser got wrong compliance type")
+	}
+
+	if !s.CardRevisionDate().Equal(time.Date(2008, 6, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Card status parser got wrong card revision date")
+	}
+
+	if !s.HazmatExpiryDate().Equal(time.Date(2009, 6, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Card status parser got wrong HAZMAT expiry date")
+	}
+
+	if !s.LimitedDuration() {
+		t.Error("Card status parser got wrong limited duration")
+	}
+
+	if s.OrganDonor() || s.Veteran() {
+		t.Error("Card status parser invented missing indicators")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDCGUSA\nDDAX\nDDK1\nDDL1", "636000", 8, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Card status parser failed")
+	}
+
+	if s.ComplianceType() != ComplianceTypeUnknown {
+		t.Error("Card status parser got wrong unknown compliance type")
+	}
+
+	if !s.OrganDonor() {
+		t.Error("Card status parser got wrong organ donor indicator")
+	}
+
+	if !s.Veteran() {
+		t.Error("Card status parser got wrong veteran indicator")
+	}
+
+	if !s.HazmatExpiryDate().IsZero() {
+		t.Error("Card status parser invented missing HAZMAT expiry date")
+	}
+}
+
+func TestAgeUntilDates(t *testing.T) {
+	s, err := parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDCGUSA\nDBB06071986\nDDH06072004\nDDI06072005\nDDJ06082007", "636000", 8, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Age date parser failed")
+	}
+
+	if !s.Under18Until().Equal(time.Date(2004, 6, 7, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Age date parser got wrong under 18 date")
+	}
+
+	if !s.Under19Until().Equal(time.Date(2005, 6, 7, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Age date parser got wrong under 19 date")
+	}
+
+	// The card is allowed to disagree with the arithmetic.
+	if !s.Under21Until().Equal(time.Date(2007, 6, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Age date parser got wrong under 21 date")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDCGUSA\nDBB02292004", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Age date parser failed")
+	}
+
+	if !s.Under18Until().Equal(time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Age date parser did not compute under 18 date")
+	}
+
+	if !s.Under21Until().Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Age date parser did not compute under 21 date")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDCGUSA", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Age date parser failed")
+	}
+
+	if !s.Under18Until().IsZero() {
+		t.Error("Age date parser invented under 18 date")
+	}
+}
+
+func TestAddresses(t *testing.T) {
+	s, err := parseDataV1("DLDAQ0123456789ABC\nDAAPUBLIC,JOHN,Q\nDAG123 MAIN STREET\nDAHAPT 4\nDAIANYTOWN\nDAJVA\nDAK123459999  \nDAL1 RURAL ROUTE\nDANNOWHERE\nDAOVA\nDAP12346     \nDBA20011201\nDBB19761123", "636000", ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Address parser failed")
+	}
+
+	if s.Street() != "123 MAIN STREET" || s.MailingAddress().Street2() != "APT 4" || s.City() != "ANYTOWN" {
+		t.Error("Address parser got wrong mailing address")
+	}
+
+	if s.ResidenceAddress().Street() != "1 RURAL ROUTE" || s.ResidenceAddress().City() != "NOWHERE" || s.ResidenceAddress().State() != "VA" || s.ResidenceAddress().Postal() != "12346" {
+		t.Error("Address parser got wrong residence address")
+	}
+
+	if hasWarning(s, WarningResidenceAddress, "DAL") {
+		t.Error("Address parser substituted residence address unnecessarily")
+	}
+
+	s, err = parseDataV1("DLDAQ0123456789ABC\nDAAJOHN Q PUBLIC\nDAL123 MAIN STREET\nDANANYTOWN\nDAOCO\nDAP80202\nDBA20011201\nDBB19761123", ColoradoIssuerId, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Address parser failed on Colorado data")
+	}
+
+	if s.Street() != "123 MAIN STREET" || s.City() != "ANYTOWN" || s.State() != "CO" || s.Postal() != "80202" {
+		t.Error("Address parser did not fall back to residence address")
+	}
+
+	if s.ResidenceAddress().Street() != "123 MAIN STREET" {
+		t.Error("Address parser lost residence address")
+	}
+
+	if !hasWarning(s, WarningResidenceAddress, "DAL") {
+		t.Error("Address parser did not report residence address substitution")
+	}
+
+	s, err = parseDataV1("DLDAQ0123456789ABC\nDAAPUBLIC,JOHN,Q\nDAGPO BOX 1\nDAL123 MAIN ST\nDAMAPT 4\nDANANYTOWN\nDAOCO\nDAP80202", ColoradoIssuerId, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Address parser failed on partial mailing address")
+	}
+
+	if s.Street() != "PO BOX 1" || s.MailingAddress().Street2() != "" || s.City() != "" || s.Postal() != "" {
+		t.Error("Address parser mixed residence address into partial mailing address")
+	}
+
+	if hasWarning(s, WarningResidenceAddress, "DAL") {
+		t.Error("Address parser reported substitution into partial mailing address")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDAG2300 WEST BROAD STREET\nDAHSUITE 100\nDAIRICHMOND\nDAJVA\nDAK232690000\nDAL1 MAIN STREET\nDAP232201234\nDCGUSA", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Address parser failed")
+	}
+
+	if s.MailingAddress().Street2() != "SUITE 100" || s.Postal() != "23269" {
+		t.Error("Address parser got wrong V4 mailing address")
+	}
+
+	if s.ResidenceAddress().Street() != "1 MAIN STREET" || s.ResidenceAddress().Postal() != "23220+1234" {
+		t.Error("Address parser got wrong V4 residence address")
+	}
+}
+
+func TestDemographics(t *testing.T) {
+	s, err := Parse("@\n\x1e\rANSI 636015030002DL00410217ZT02020022DLDCAB\nDCBLP\nDCDP\nDBA04052018\nDCSJONES\nDCTJAMES ROBERT R\nDBD07082012\nDBB10111978\nDBC1\nDAYBRO\nDAU 70 IN\nDAG123 SOME STREET\nDAICITY 12\nDAJTX\nDAK902100000  \nDAQ22334455\nDCF11111111111111111111\nDCGUSA\nDCHB   \nDAZBRO\nDCU\rZTZTA220\nZTBW\n")
+
+	if err != nil {
+		t.Fatal("Demographics parser failed")
+	}
+
+	if s.FederalVehicleCodes() != "B" {
+		t.Error("Demographics parser got wrong federal vehicle codes")
+	}
+
+	if classes := s.FederalVehicleClasses(); len(classes) != 1 || classes[0].Code() != "B" || classes[0].Description() != standardVehicleClasses["B"] {
+		t.Error("Demographics parser got wrong federal vehicle classes")
+	}
+
+	s, err = parseDataV3("DLDAQ22334455\nDCSJONES\nDAFDR\nDCIPHILADELPHIA, PA\nDCLAP\nDCGUSA", "636015", ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Demographics parser failed")
+	}
+
+	if s.NamePrefix() != "DR" {
+		t.Error("Demographics parser got wrong name prefix")
+	}
+
+	if s.PlaceOfBirth() != "PHILADELPHIA, PA" {
+		t.Error("Demographics parser got wrong place of birth")
+	}
+
+	if s.Race() != RaceAsianOrPacificIslander {
+		t.Error("Demographics parser got wrong race")
+	}
+
+	if parseRace("XX") != RaceUnknown || parseRace("") != RaceNone {
+		t.Error("Race parser got wrong fallback values")
+	}
+}
+
+func TestVehicleCodes(t *testing.T) {
+	s, err := Parse("@\n\x1e\rANSI 636000070002DL00410282ZV03190008DLDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB06071986\nDBA12102012\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAM\nDDB06062008\nDDC06062009\nDDD1\rZVZVA01\r")
+
+	if err != nil {
+		t.Fatal("Vehicle code parser failed")
+	}
+
+	if classes := s.VehicleClasses(); len(classes) != 1 || classes[0].Code() != "D" || classes[0].Description() != "Regular operator" {
+		t.Error("Vehicle code parser got wrong vehicle class")
+	}
+
+	if restrictions := s.Restrictions(); len(restrictions) != 1 || restrictions[0].Description() != "CDL intrastate only" {
+		t.Error("Vehicle code parser got wrong restrictions")
+	}
+
+	endorsements := s.Endorsements()
+
+	if len(endorsements) != 2 || endorsements[0].Description() != "Passenger" || endorsements[1].Description() != "Hazardous materials" {
+		t.Error("Vehicle code parser got wrong endorsements")
+	}
+
+	RegisterVehicleCodes("636000", VehicleCodeTypeRestriction, map[string]string{"K": "Ignition interlock"})
+	defer RegisterVehicleCodes("636000", VehicleCodeTypeRestriction, nil)
+
+	if restrictions := s.Restrictions(); restrictions[0].Description() != "Ignition interlock" {
+		t.Error("Vehicle code parser did not use issuer override")
+	}
+
+	if endorsements := s.Endorsements(); endorsements[0].Description() != "Passenger" {
+		t.Error("Vehicle code parser applied override to wrong code type")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDCMC\nDCNH\nDCOB,E\nDCGUSA", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Vehicle code parser failed")
+	}
+
+	if classes := s.StandardVehicleClasses(); len(classes) != 1 || classes[0].Code() != "C" {
+		t.Error("Vehicle code parser got wrong standard vehicle class")
+	}
+
+	if restrictions := s.StandardRestrictions(); len(restrictions) != 2 || restrictions[0].Description() != "Corrective lenses" || restrictions[1].Code() != "E" {
+		t.Error("Vehicle code parser got wrong standard restrictions")
+	}
+
+	RegisterVehicleCodes("636001", VehicleCodeTypeClass, map[string]string{"DJ": "Junior"})
+	defer RegisterVehicleCodes("636001", VehicleCodeTypeClass, nil)
+
+	if codes := DecodeVehicleCodes("DJ", VehicleCodeTypeClass, "636001"); len(codes) != 1 || codes[0].Description() != "Junior" {
+		t.Error("Vehicle code parser split a multi-character issuer code")
+	}
+
+	if codes := DecodeVehicleCodes("MDJ", VehicleCodeTypeClass, "636001"); len(codes) != 2 || codes[0].Code() != "M" || codes[1].Code() != "DJ" {
+		t.Error("Vehicle code parser got wrong run-together issuer codes")
+	}
+
+	if codes := DecodeVehicleCodes("DJ", VehicleCodeTypeClass, "636000"); len(codes) != 2 || codes[0].Description() != "Regular operator" {
+		t.Error("Vehicle code parser applied issuer code to wrong issuer")
+	}
+
+	if len(DecodeVehicleCodes("NONE", VehicleCodeTypeEndorsement, "")) != 0 {
+		t.Error("Vehicle code parser decoded an empty field")
+	}
+
+	if codes := DecodeVehicleCodes("Q", VehicleCodeTypeRestriction, ""); len(codes) != 1 || codes[0].Description() != "" {
+		t.Error("Vehicle code parser invented a description")
+	}
+}
+
+func TestIssuers(t *testing.T) {
+	issuer, ok := LookupIssuer("636000")
+
+	if !ok || issuer.Name() != "Virginia" || issuer.Code() != "VA" || issuer.Country() != "USA" || issuer.Region() != 2 {
+		t.Error("Issuer registry got wrong details for Virginia")
+	}
+
+	if _, ok := LookupIssuer("999999"); ok {
+		t.Error("Issuer registry found an issuer that does not exist")
+	}
+
+	err := LoadIssuersJSON(strings.NewReader(`[{"iin": "999999", "name": "Test State", "code": "TS", "country": "USA", "region": 1}]`))
+
+	if err != nil {
+		t.Fatal("Issuer registry failed to load JSON")
+	}
+
+	defer delete(issuers, "999999")
+
+	if issuer, ok := LookupIssuer("999999"); !ok || issuer.Name() != "Test State" || issuer.Region() != 1 {
+		t.Error("Issuer registry got wrong details for JSON issuer")
+	}
+
+	err = LoadIssuersCSV(strings.NewReader("iin,name,code,country,region\n999998,Another State,AS,MEX,\n636000,Commonwealth of Virginia,VA,USA,2\n"))
+
+	if err != nil {
+		t.Fatal("Issuer registry failed to load CSV")
+	}
+
+	defer delete(issuers, "999998")
+	defer RegisterIssuer(issuer)
+
+	if issuer, ok := LookupIssuer("999998"); !ok || issuer.Country() != "MEX" || issuer.Region() != 0 {
+		t.Error("Issuer registry got wrong details for CSV issuer")
+	}
+
+	s, err := parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDCGUSA", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Issuer registry parser failed")
+	}
+
+	if s.IssuerName() != "Commonwealth of Virginia" {
+		t.Error("Issuer registry did not override existing issuer")
+	}
+
+	if issuer, ok := s.Issuer(); !ok || issuer.Code() != "VA" {
+		t.Error("Issuer registry got wrong issuer for licence")
+	}
+
+	if LoadIssuersCSV(strings.NewReader("999997,Bad State,BS,USA,north\n")) == nil {
+		t.Error("Issuer registry accepted an invalid region")
+	}
+
+	if _, ok := LookupIssuer("999997"); ok {
+		t.Error("Issuer registry registered issuers from invalid CSV")
+	}
+
+	if LoadIssuersJSON(strings.NewReader(`[{"name": "No IIN"}]`)) == nil {
+		t.Error("Issuer registry accepted an issuer without an IIN")
+	}
+}
+
+func TestConsistency(t *testing.T) {
+	s, err := Parse("@\n\x1e\rANSI 636000070002DL00410282ZV03190008DLDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB06071986\nDBA12102012\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAM\nDDB06062008\nDDC06062009\nDDD1\rZVZVA01\r")
+
+	if err != nil {
+		t.Fatal("Consistency checker parser failed")
+	}
+
+	if warnings := CheckConsistency(s); len(warnings) != 0 {
+		t.Error("Consistency checker reported a problem with a consistent licence")
+	}
+
+	s, err = Parse("@\n\x1e\rANSI 636000070002DL00410282ZV03190008DLDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD20080606\nDBB19860607\nDBA20121210\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGCAN\nDCK123456789\nDDAM\nDDB20080606\nDDC20090606\nDDD1\rZVZVA01\r")
+
+	if err != nil {
+		t.Fatal("Consistency checker parser failed")
+	}
+
+	warnings := CheckConsistency(s)
+
+	found := func(code WarningCode, element string) bool {
+		for _, warning := range warnings {
+			if warning.Code() == code && warning.Element() == element {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if !found(WarningIssuerCountry, "DCG") {
+		t.Error("Consistency checker did not report wrong country")
+	}
+
+	if !found(WarningIssuerDateFormat, "DBB") || !found(WarningIssuerDateFormat, "DBA") || !found(WarningIssuerDateFormat, "DBD") {
+		t.Error("Consistency checker did not report wrong date format")
+	}
+
+	if found(WarningDateFormat, "DBB") {
+		t.Error("Consistency checker reused the parser's date format warning")
+	}
+
+	if found(WarningIssuerState, "DAJ") {
+		t.Error("Consistency checker reported a matching state")
+	}
+
+	if isDateCCYYMMDD("18500101") || !isDateCCYYMMDD("18800101") {
+		t.Error("Consistency checker used wrong plausible year range")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDAJMD\nDCGUSA", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Consistency checker parser failed")
+	}
+
+	if warnings := CheckConsistency(s); len(warnings) != 1 || warnings[0].Code() != WarningIssuerState {
+		t.Error("Consistency checker did not report wrong state")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDCGUSA", "999999", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Consistency checker parser failed")
+	}
+
+	if warnings := CheckConsistency(s); len(warnings) != 1 || warnings[0].Code() != WarningUnknownIssuer {
+		t.Error("Consistency checker did not report unknown issuer")
+	}
+}
+
+func TestPostalCodes(t *testing.T) {
+	tests := []struct {
+		data      string
+		canonical string
+		zip5      string
+		plus4     string
+		valid     bool
+	}{
+		{"12345", "12345", "12345", "", true},
+		{"123459999  ", "12345+9999", "12345", "9999", true},
+		{"12345-6789", "12345+6789", "12345", "6789", true},
+		{"12345+6789", "12345+6789", "12345", "6789", true},
+		{"23269000000", "23269", "23269", "", true},
+		{"2326", "2326", "", "", false},
+		{"ABCDE", "ABCDE", "", "", false},
+	}
+
+	for _, test := range tests {
+		postal := ParsePostalCode(test.data)
+
+		if postal.String() != test.canonical || postal.ZIP5() != test.zip5 || postal.Plus4() != test.plus4 || postal.IsValid() != test.valid {
+			t.Error("Postal code parser got wrong result for " + test.data)
+		}
+	}
+
+	postal := ParsePostalCode("k1a0b1     ")
+
+	if !postal.IsCanadian() || postal.FSA() != "K1A" || postal.LDU() != "0B1" || postal.String() != "K1A 0B1" {
+		t.Error("Postal code parser got wrong Canadian postal code")
+	}
+
+	if ParsePostalCode("D1A 0B1").IsValid() {
+		t.Error("Postal code parser accepted invalid Canadian postal code")
+	}
+
+	s, err := parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDAKT2P 1J9\nDCGCAN", "604432", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Postal code parser failed")
+	}
+
+	if s.Postal() != "T2P 1J9" || s.PostalCode().FSA() != "T2P" {
+		t.Error("V4 parser got wrong Canadian postal code")
+	}
+}
+
+func TestDateStatus(t *testing.T) {
+	s, err := parseDataV1("DLDAQ0123456789ABC\nDAAPUBLIC,JOHN,Q\nDBA20010230\nDBB19700101", "636000", ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Date parser failed")
+	}
+
+	if !s.DateOfBirth().IsPresent() || s.DateOfBirth().Year() != 1970 || s.DateOfBirth().Raw() != "19700101" {
+		t.Error("Date parser got wrong 1970 date of birth")
+	}
+
+	if !s.ExpiryDate().IsInvalid() || s.ExpiryDate().Raw() != "20010230" || !s.ExpiryDate().IsZero() {
+		t.Error("Date parser accepted the 30th of February")
+	}
+
+	if !s.IssueDate().IsAbsent() {
+		t.Error("Date parser invented missing issue date")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDBB06071986\nDBA13102012", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Date parser failed")
+	}
+
+	if !s.DateOfBirth().IsPresent() || s.DateOfBirth().Year() != 1986 || !s.ExpiryDate().IsInvalid() || s.ExpiryDate().Raw() != "13102012" {
+		t.Error("Date parser let a date that fits no format spoil the others")
+	}
+
+	if parseDateV3("13102012", DateFormatMMDDCCYY).Status() != DateStatusInvalid {
+		t.Error("Date parser accepted month 13")
+	}
+
+	if !parseDateV3("20000229", DateFormatCCYYMMDD).IsPresent() || parseDateV3("19000229", DateFormatCCYYMMDD).IsPresent() {
+		t.Error("Date parser got leap years wrong")
+	}
+
+	if !parseDateV2("0229").IsInvalid() {
+		t.Error("Date parser accepted a short date")
+	}
+
+	_, err = ParseWithOptions("@\n\x1e\rANSI 636000070002DL00410281ZV03220008DLDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB01011970\nDBA12102012\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAM\nDDB06062008\nDDC06062009\nDDD1\rZVZVA01\r", ParseOptions{Mode: ParseModeStrict})
+
+	if err != nil {
+		t.Error("Strict parser rejected a licencee born on the 1st of January 1970")
+	}
+}
+
+func TestDateFormatResolver(t *testing.T) {
+	s, err := parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDBD06062008\nDBB06071986\nDBA12102012", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Date format resolver parser failed")
+	}
+
+	if s.DateFormatRule() != DateFormatRuleIssuer || s.DateFormat() != DateFormatMMDDCCYY {
+		t.Error("Date format resolver did not use issuer country")
+	}
+
+	if s.DateOfBirth().Year() != 1986 || s.DateOfBirth().Month() != 6 || s.DateOfBirth().Day() != 7 {
+		t.Error("Date format resolver got wrong date of birth")
+	}
+
+	if !hasWarning(s, WarningDateFormat, "DCG") {
+		t.Error("Date format resolver did not report missing country")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDBD20080606\nDBB19860607\nDBA20121210\nDCGUSA", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Date format resolver parser failed")
+	}
+
+	if s.DateFormatRule() != DateFormatRuleUnambiguous || s.DateFormat() != DateFormatCCYYMMDD || s.ExpiryDate().Year() != 2012 {
+		t.Error("Date format resolver did not recover from wrong country")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDBD06062008\nDBB06071986\nDBA12102012\nDCGUSA", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Date format resolver parser failed")
+	}
+
+	if s.DateFormatRule() != DateFormatRuleCountry || hasWarning(s, WarningDateFormat, "DCG") {
+		t.Error("Date format resolver did not trust a correct country")
+	}
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	dates := licenceDates{dateOfBirth: "19801212", issueDate: "20101111", expiryDate: "20201111"}
+
+	if format, rule := resolveDateFormat(dates, "", "999999"); format != DateFormatCCYYMMDD || rule != DateFormatRuleUnambiguous {
+		t.Error("Date format resolver got wrong unambiguous format")
+	}
+
+	dates = licenceDates{dateOfBirth: "12011990", issueDate: "11012000", expiryDate: "11012001"}
+
+	if format, rule := resolveDateFormat(dates, "", "999999"); format != DateFormatMMDDCCYY || rule != DateFormatRuleUnambiguous {
+		t.Error("Date format resolver got wrong unambiguous US format")
+	}
+
+	if !datesOrdered(dates, DateFormatMMDDCCYY, now) {
+		t.Error("Date format resolver rejected ordered dates")
+	}
+
+	if datesOrdered(licenceDates{dateOfBirth: "01012030"}, DateFormatMMDDCCYY, now) || datesOrdered(licenceDates{issueDate: "01012020", expiryDate: "01012010"}, DateFormatMMDDCCYY, now) {
+		t.Error("Date format resolver accepted dates out of order")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDBD06062008\nDBB06072010\nDBA12102012\nDCGUSA", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Date format resolver parser failed")
+	}
+
+	if !hasWarning(s, WarningDateOrder, "") || s.DateOfBirth().Year() != 2010 {
+		t.Error("Date format resolver did not report dates out of order")
+	}
+
+	if format, rule := resolveDateFormat(licenceDates{}, "", "999999"); format != DateFormatNone || rule != DateFormatRuleNone {
+		t.Error("Date format resolver invented a format")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDBD06062008\nDBB06071986\nDBA12102012\nDDBXXXX1234", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Date format resolver parser failed")
+	}
+
+	if s.DateFormatRule() != DateFormatRuleIssuer || !s.DateOfBirth().IsPresent() || !s.ExpiryDate().IsPresent() || !s.IssueDate().IsPresent() || !s.CardRevisionDate().IsInvalid() {
+		t.Error("Date format resolver was thrown by a malformed optional date")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDBD06062008\nDBBXXXX1234\nDBA12102012\nDCGUSA\nDDBXXXX1234", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Date format resolver parser failed")
+	}
+
+	if s.DateFormatRule() != DateFormatRuleCountry || !s.IssueDate().IsPresent() || !s.DateOfBirth().IsInvalid() || hasWarning(s, WarningDateFormat, "DCG") {
+		t.Error("Date format resolver did not trust the country alongside malformed dates")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDBD13132008\nDBB99999999\nDCGUSA", "999999", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Date format resolver parser failed")
+	}
+
+	if s.DateFormatRule() != DateFormatRuleNone || !hasWarning(s, WarningDateFormat, "DCG") {
+		t.Error("Date format resolver did not report dates that fit no format")
+	}
+}
+
+func TestAgeAndExpiry(t *testing.T) {
+	s, err := parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDCGUSA\nDBB02292004\nDBA12102012", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Age parser failed")
+	}
+
+	if age, ok := s.AgeOn(time.Date(2022, 2, 28, 12, 0, 0, 0, time.UTC)); !ok || age != 17 {
+		t.Error("Age parser got wrong age on the day before a leap-day birthday")
+	}
+
+	if age, _ := s.AgeOn(time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)); age != 18 {
+		t.Error("Age parser got wrong age on a leap-day birthday")
+	}
+
+	if age, _ := s.AgeOn(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)); age != 20 {
+		t.Error("Age parser got wrong age on a leap-day birthday in a leap year")
+	}
+
+	if s.IsOfAge(21, time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC)) || !s.IsOfAge(21, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Age parser got wrong result for 21")
+	}
+
+	if !s.IsOfAge(16, time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Age parser got wrong result for 16")
+	}
+
+	// 11pm on the last day of February in Los Angeles is already March in UTC.
+	losAngeles := time.FixedZone("PST", -8*60*60)
+	scanned := time.Date(2025, 2, 28, 23, 0, 0, 0, losAngeles)
+
+	if !s.IsOfAge(21, scanned.UTC()) || s.IsOfAge(21, scanned) {
+		t.Error("Age parser did not use the reference time's location")
+	}
+
+	if s.IsExpiredOn(time.Date(2012, 12, 10, 23, 59, 0, 0, time.UTC), 0) {
+		t.Error("Expiry check expired a licence on its expiry date")
+	}
+
+	if !s.IsExpiredOn(time.Date(2012, 12, 11, 0, 0, 0, 0, time.UTC), 0) {
+		t.Error("Expiry check did not expire a licence after its expiry date")
+	}
+
+	if s.IsExpiredOn(time.Date(2013, 1, 9, 0, 0, 0, 0, time.UTC), 30) || !s.IsExpiredOn(time.Date(2013, 1, 10, 0, 0, 0, 0, time.UTC), 30) {
+		t.Error("Expiry check got grace period wrong")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDCGUSA", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Age parser failed")
+	}
+
+	if _, ok := s.AgeOn(time.Now()); ok || s.IsOfAge(18, time.Now()) || !s.IsExpiredOn(time.Now(), 0) {
+		t.Error("Age parser got wrong results for missing dates")
+	}
+
+	s, err = parseDataV4("DLDAQT64235789\nDCSSAMPLE\nDCGUSA\nDBB01011980\nDDJ99999999", "636000", 4, ParseOptions{})
+
+	if err != nil {
+		t.Fatal("Age parser failed")
+	}
+
+	if !s.Under21Until().IsInvalid() || !s.IsOfAge(21, time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)) || s.IsOfAge(21, time.Date(2000, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Age parser did not fall back to the date of birth for an invalid under 21 date")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	l := new(DLIDLicense)
+
+	l.SetIssuerId("636000")
+	l.SetCustomerId("T64235789")
+	l.SetLastName("SAMPLE")
+	l.SetFirstName("MICHAEL")
+	l.SetMiddleNames([]string{"J"})
+	l.SetStreet("2300 WEST BROAD STREET")
+	l.SetCity("RICHMOND")
+	l.SetState("VA")
+	l.SetPostal("23269")
+	l.SetDateOfBirth(NewDate(time.Date(1986, 6, 2, 0, 0, 0, 0, time.UTC)))
+	l.SetExpiryDate(NewDate(time.Date(2012, 12, 10, 0, 0, 0, 0, time.UTC)))
+	l.SetIssueDate(NewDate(time.Date(2008, 12, 10, 0, 0, 0, 0, time.UTC)))
+	l.SetSex(DriverSexMale)
+	l.SetHeight(NewHeight(69, MeasurementUnitImperial))
+	l.SetEyeColour(EyeColourBlue)
+
+	data, err := Encode(l, 8)
+
+	if err != nil {
+		t.Fatal("Encoder failed")
+	}
+
+	if !strings.HasPrefix(data, "@\n\x1e\rANSI 636000080001DL00310") {
+		t.Error("Encoder wrote the wrong header")
+	}
+
+	if !strings.Contains(data, "\nDAK232690000\n") || !strings.Contains(data, "\nDBB06021986\n") || !strings.Contains(data, "\nDAU069 in\n") {
+		t.Error("Encoder wrote the wrong elements")
+	}
+
+	s, err := ParseWithOptions(data, ParseOptions{Mode: ParseModeStrict})
+
+	if err != nil {
+		t.Fatal("Encoder output failed to parse")
+	}
+
+	if s.LastName() != "SAMPLE" || s.FirstName() != "MICHAEL" || s.Postal() != "23269" || !s.DateOfBirth().Equal(l.DateOfBirth().Time) {
+		t.Error("Encoder output parsed to the wrong licence")
+	}
+
+	v1, err := Encode(l, 1)
+
+	if err != nil || !strings.Contains(v1, "\nDAASAMPLE,MICHAEL,J\n") || !strings.Contains(v1, "\nDAU509\n") || !strings.Contains(v1, "\nDBB19860602\n") {
+		t.Error("Encoder wrote the wrong version 1 elements")
+	}
+
+	if _, err := Encode(l, 11); err != ErrUnsupportedVersion {
+		t.Error("Encoder accepted an unsupported version")
+	}
+
+	l.SetIssuerId("ABC")
+
+	if _, err := Encode(l, 8); err != ErrInvalidIssuer {
+		t.Error("Encoder accepted an invalid issuer")
+	}
+
+	l.SetIssuerId("636000")
+	l.SetCity("RICHMOND\nDAJXX")
+
+	if _, err := Encode(l, 8); err != ErrInvalidElement {
+		t.Error("Encoder accepted a value containing a separator")
+	}
+
+	l.SetCity("RICHMOND\r")
+
+	if _, err := Encode(l, 1); err != ErrInvalidElement {
+		t.Error("Encoder accepted a value containing a terminator")
+	}
+}
+
+// The round trip tests build random licences for every version and every
+// known issuer, encode them, parse them back and check that nothing was lost
+// beyond the fields that the version can't hold, which expectedRoundTrip
+// lists.  Conversions that change a value rather than lose it are checked
+// separately by TestRoundTripLossy.  The seed is fixed so that failures can be
+// reproduced.
+
+const roundTripLicencesPerIssuer = 5
+
+var roundTripLetters = "ABCEGHJKLMNPRSTVXY"
+
+func randomWord(r *rand.Rand, min int, max int) string {
+	word := make([]byte, min+r.Intn(max-min+1))
+
+	for i := range word {
+		word[i] = roundTripLetters[r.Intn(len(roundTripLetters))]
+	}
+
+	return string(word)
+}
+
+func randomDigits(r *rand.Rand, n int) string {
+	digits := make([]byte, n)
+
+	for i := range digits {
+		digits[i] = byte('0' + r.Intn(10))
+	}
+
+	return string(digits)
+}
+
+func randomDate(r *rand.Rand, fromYear int, toYear int) Date {
+	start := time.Date(fromYear, 1, 1, 0, 0, 0, 0, time.UTC)
+	days := int(time.Date(toYear+1, 1, 1, 0, 0, 0, 0, time.UTC).Sub(start).Hours() / 24)
+
+	return NewDate(start.AddDate(0, 0, r.Intn(days)))
+}
+
+func randomPostal(r *rand.Rand, canadian bool) string {
+	if canadian {
+		letter := func() string {
+			return string(roundTripLetters[r.Intn(len(roundTripLetters))])
+		}
+
+		digit := func() string {
+			return randomDigits(r, 1)
+		}
+
+		return letter() + digit() + letter() + " " + digit() + letter() + digit()
+	}
+
+	// A +4 of "0000" is padding and is thrown away by the parser, so it would
+	// never come back.
+	if r.Intn(2) == 0 {
+		return randomDigits(r, 5)
+	}
+
+	return randomDigits(r, 5) + "-" + strconv.Itoa(1000+r.Intn(9000))
+}
+
+func randomAddress(r *rand.Rand, canadian bool) Address {
+	var address Address
+
+	address.SetStreet(strconv.Itoa(1+r.Intn(9999)) + " " + randomWord(r, 3, 10) + " STREET")
+	address.SetCity(randomWord(r, 3, 12))
+	address.SetState(randomWord(r, 2, 2))
+	address.SetPostal(randomPostal(r, canadian))
+
+	if r.Intn(2) == 0 {
+		address.SetStreet2("APT " + randomDigits(r, 2))
+	}
+
+	return address
+}
+
+func randomEyeColour(r *rand.Rand) EyeColour {
+	colours := []EyeColour{EyeColourBlack, EyeColourBlue, EyeColourBrown, EyeColourGrey, EyeColourGreen, EyeColourHazel, EyeColourMaroon, EyeColourPink, EyeColourDichromatic, EyeColourUnknown}
+	return colours[r.Intn(len(colours))]
+}
+
+func randomHairColour(r *rand.Rand) HairColour {
+	colours := []HairColour{HairColourBald, HairColourBlack, HairColourBlond, HairColourBrown, HairColourGrey, HairColourRed, HairColourSandy, HairColourWhite, HairColourUnknown}
+	return colours[r.Intn(len(colours))]
+}
+
+func randomLicence(r *rand.Rand, issuer Issuer) *DLIDLicense {
+
+	l := new(DLIDLicense)
+
+	canadian := issuer.Country() == "CAN"
+
+	l.SetIssuerId(issuer.IIN())
+	l.SetDocumentType([]DocumentType{DocumentTypeDriverLicense, DocumentTypeIdentificationCard, DocumentTypeDriverLicenseAndIdentificationCard}[r.Intn(3)])
+	l.SetCustomerId(randomWord(r, 1, 1) + randomDigits(r, 8))
+	l.SetNamePrefix([]string{"", "MR", "MS", "DR"}[r.Intn(4)])
+	l.SetLastName(randomWord(r, 2, 12))
+	l.SetFirstName(randomWord(r, 2, 10))
+	l.SetNameSuffix([]string{"", "JR", "SR", "III"}[r.Intn(4)])
+
+	if r.Intn(2) == 0 {
+		l.SetMiddleNames([]string{randomWord(r, 1, 8)})
+	}
+
+	l.SetCountry(issuer.Country())
+
+	if len(l.Country()) == 0 {
+		l.SetCountry("USA")
+	}
+
+	l.SetMailingAddress(randomAddress(r, canadian))
+
+	if r.Intn(2) == 0 {
+		l.SetResidenceAddress(randomAddress(r, canadian))
+	}
+
+	l.SetDateOfBirth(randomDate(r, 1930, 2000))
+	l.SetIssueDate(randomDate(r, 2010, 2019))
+	l.SetExpiryDate(randomDate(r, 2020, 2030))
+	l.SetSex([]DriverSex{DriverSexMale, DriverSexFemale, DriverSexNotSpecified}[r.Intn(3)])
+	l.SetSocialSecurityNumber(randomDigits(r, 9))
+	l.SetVehicleClass(randomWord(r, 1, 1))
+	l.SetRestrictionCodes(randomWord(r, 1, 2))
+	l.SetEndorsementCodes(randomWord(r, 1, 2))
+	l.SetStandardVehicleClass(randomWord(r, 1, 1))
+	l.SetStandardRestrictionCodes(randomWord(r, 1, 2))
+	l.SetStandardEndorsementCodes(randomWord(r, 1, 2))
+	l.SetDocumentDiscriminator(randomDigits(r, 12))
+	l.SetInventoryControl(randomDigits(r, 10))
+	l.SetAuditInformation(randomWord(r, 4, 8))
+	l.SetPlaceOfBirth(randomWord(r, 4, 12))
+	l.SetRace([]Race{RaceAlaskanOrAmericanIndian, RaceAsianOrPacificIslander, RaceBlack, RaceHispanicOrigin, RaceNonHispanic, RaceWhite, RaceUnknown}[r.Intn(7)])
+	l.SetFederalVehicleCodes(randomWord(r, 4, 4))
+	l.SetEyeColour(randomEyeColour(r))
+	l.SetHairColour(randomHairColour(r))
+	l.SetWeightRange(WeightRange0 + WeightRange(r.Intn(10)))
+
+	if r.Intn(2) == 0 {
+		l.SetHeight(NewHeight(48+r.Intn(36), MeasurementUnitImperial))
+		l.SetWeight(NewWeight(90+r.Intn(250), MeasurementUnitImperial))
+	} else {
+		l.SetHeight(NewHeight(120+r.Intn(90), MeasurementUnitMetric))
+		l.SetWeight(NewWeight(40+r.Intn(110), MeasurementUnitMetric))
+	}
+
+	if r.Intn(2) == 0 {
+		l.SetAliases([]Alias{NewAlias(randomWord(r, 2, 10), randomWord(r, 2, 10), "JR")})
+	}
+
+	truncations := []Truncation{TruncationTruncated, TruncationNotTruncated, TruncationUnknown}
+
+	l.SetLastNameTruncation(truncations[r.Intn(3)])
+	l.SetFirstNameTruncation(truncations[r.Intn(3)])
+	l.SetMiddleNameTruncation(truncations[r.Intn(3)])
+	l.SetComplianceType([]ComplianceType{ComplianceTypeFullyCompliant, ComplianceTypeMateriallyCompliant, ComplianceTypeNonCompliant}[r.Intn(3)])
+	l.SetCardRevisionDate(randomDate(r, 2000, 2019))
+	l.SetHazmatExpiryDate(randomDate(r, 2020, 2030))
+	l.SetLimitedDuration(r.Intn(2) == 0)
+	l.SetOrganDonor(r.Intn(2) == 0)
+	l.SetVeteran(r.Intn(2) == 0)
+	l.SetUnder18Until(NewDate(l.DateOfBirth().AddDate(18, 0, 0)))
+	l.SetUnder19Until(NewDate(l.DateOfBirth().AddDate(19, 0, 0)))
+	l.SetUnder21Until(NewDate(l.DateOfBirth().AddDate(21, 0, 0)))
+
+	return l
+}
+
+// expectedRoundTrip returns what a licence should look like after being
+// encoded with the given version and parsed back.  Every field that the
+// version can't hold is lost, and this is the list of them.
+func expectedRoundTrip(l *DLIDLicense, version int) *DLIDLicense {
+
+	expected := *l
+
+	// Version 1 has no country element; the parser assumes USA.  Version 2
+	// has none either, and the parser doesn't assume anything.
+	switch version {
+	case 1:
+		expected.SetCountry("USA")
+	case 2:
+		expected.SetCountry("")
+	}
+
+	// Only version 1 has the social security number.
+	if version != 1 {
+		expected.SetSocialSecurityNumber("")
+	}
+
+	// Versions 2 and 3 have no name suffix element, and version 4 dropped the
+	// name prefix.
+	if version == 2 || version == 3 {
+		expected.SetNameSuffix("")
+	}
+
+	if version >= 4 {
+		expected.SetNamePrefix("")
+	}
+
+	// "Not specified" only joined the sex codes in version 10.
+	if version < 10 && expected.Sex() == DriverSexNotSpecified {
+		expected.SetSex(DriverSexNone)
+	}
+
+	// Version 2 added the card identifiers, aliases, demographics, weight
+	// ranges and the standard vehicle codes.
+	if version == 1 {
+		expected.SetStandardVehicleClass("")
+		expected.SetStandardRestrictionCodes("")
+		expected.SetStandardEndorsementCodes("")
+		expected.SetDocumentDiscriminator("")
+		expected.SetInventoryControl("")
+		expected.SetAuditInformation("")
+		expected.SetPlaceOfBirth("")
+		expected.SetRace(RaceNone)
+		expected.SetFederalVehicleCodes("")
+		expected.SetWeightRange(WeightRangeNone)
+		expected.SetAliases(nil)
+	}
+
+	// Version 4 added name truncation and everything about the card's status.
+	if version < 4 {
+		expected.SetLastNameTruncation(TruncationNone)
+		expected.SetFirstNameTruncation(TruncationNone)
+		expected.SetMiddleNameTruncation(TruncationNone)
+		expected.SetComplianceType(ComplianceTypeNone)
+		expected.SetCardRevisionDate(Date{})
+		expected.SetHazmatExpiryDate(Date{})
+		expected.SetLimitedDuration(false)
+		expected.SetOrganDonor(false)
+		expected.SetVeteran(false)
+		expected.SetUnder18Until(Date{})
+		expected.SetUnder19Until(Date{})
+		expected.SetUnder21Until(Date{})
+	}
+
+	return &expected
+}
+
+func roundTripDate(d Date) string {
+	if !d.IsPresent() {
+		return fmt.Sprint(d.Status(), d.Raw())
+	}
+
+	return d.Format("2006-01-02")
+}
+
+func roundTripAddress(a Address) string {
+	return strings.Join([]string{a.Street(), a.Street2(), a.City(), a.State(), a.Postal()}, "|")
+}
+
+// roundTripFields lists the fields that must survive a round trip, rendered
+// as strings so that differences can be reported readably.
+var roundTripFields = []struct {
+	name  string
+	field func(l *DLIDLicense) string
+}{
+	{"document type", func(l *DLIDLicense) string { return fmt.Sprint(l.DocumentType()) }},
+	{"issuer", func(l *DLIDLicense) string { return l.IssuerId() }},
+	{"country", func(l *DLIDLicense) string { return l.Country() }},
+	{"customer ID", func(l *DLIDLicense) string { return l.CustomerId() }},
+	{"last name", func(l *DLIDLicense) string { return l.LastName() }},
+	{"first name", func(l *DLIDLicense) string { return l.FirstName() }},
+	{"middle names", func(l *DLIDLicense) string { return strings.Join(l.MiddleNames(), ",") }},
+	{"name suffix", func(l *DLIDLicense) string { return l.NameSuffix() }},
+	{"name prefix", func(l *DLIDLicense) string { return l.NamePrefix() }},
+	{"truncation", func(l *DLIDLicense) string {
+		return fmt.Sprint(l.LastNameTruncation(), l.FirstNameTruncation(), l.MiddleNameTruncation())
+	}},
+	{"aliases", func(l *DLIDLicense) string { return fmt.Sprint(l.Aliases()) }},
+	{"mailing address", func(l *DLIDLicense) string { return roundTripAddress(l.MailingAddress()) }},
+	{"residence address", func(l *DLIDLicense) string { return roundTripAddress(l.ResidenceAddress()) }},
+	{"date of birth", func(l *DLIDLicense) string { return roundTripDate(l.DateOfBirth()) }},
+	{"issue date", func(l *DLIDLicense) string { return roundTripDate(l.IssueDate()) }},
+	{"expiry date", func(l *DLIDLicense) string { return roundTripDate(l.ExpiryDate()) }},
+	{"sex", func(l *DLIDLicense) string { return fmt.Sprint(l.Sex()) }},
+	{"social security number", func(l *DLIDLicense) string { return l.SocialSecurityNumber() }},
+	{"vehicle codes", func(l *DLIDLicense) string {
+		return strings.Join([]string{l.VehicleClass(), l.RestrictionCodes(), l.EndorsementCodes()}, "|")
+	}},
+	{"standard vehicle codes", func(l *DLIDLicense) string {
+		return strings.Join([]string{l.StandardVehicleClass(), l.StandardRestrictionCodes(), l.StandardEndorsementCodes()}, "|")
+	}},
+	{"card identifiers", func(l *DLIDLicense) string {
+		return strings.Join([]string{l.DocumentDiscriminator(), l.InventoryControl(), l.AuditInformation()}, "|")
+	}},
+	{"demographics", func(l *DLIDLicense) string {
+		return fmt.Sprint(l.PlaceOfBirth(), l.Race(), l.FederalVehicleCodes())
+	}},
+	{"physical description", func(l *DLIDLicense) string {
+		return fmt.Sprint(l.Height(), l.Weight(), l.WeightRange(), l.EyeColour(), l.HairColour())
+	}},
+	{"card status", func(l *DLIDLicense) string {
+		return fmt.Sprint(l.ComplianceType(), l.LimitedDuration(), l.OrganDonor(), l.Veteran())
+	}},
+	{"card dates", func(l *DLIDLicense) string {
+		return strings.Join([]string{roundTripDate(l.CardRevisionDate()), roundTripDate(l.HazmatExpiryDate())}, "|")
+	}},
+	{"age dates", func(l *DLIDLicense) string {
+		return strings.Join([]string{roundTripDate(l.under18Until), roundTripDate(l.under19Until), roundTripDate(l.under21Until)}, "|")
+	}},
+}
+
+func TestRoundTrip(t *testing.T) {
+
+	r := rand.New(rand.NewSource(1))
+
+	iins := make([]string, 0, len(issuers))
+
+	for iin := range issuers {
+		iins = append(iins, iin)
+	}
+
+	sort.Strings(iins)
+
+	for version := 1; version <= 10; version++ {
+		for _, iin := range iins {
+			for i := 0; i < roundTripLicencesPerIssuer; i++ {
+				original := randomLicence(r, issuers[iin])
+				expected := expectedRoundTrip(original, version)
+
+				data, err := Encode(original, version)
+
+				if err != nil {
+					t.Fatalf("Round trip encoder failed for version %d, issuer %s: %v", version, iin, err)
+				}
+
+				parsed, err := ParseWithOptions(data, ParseOptions{Mode: ParseModeStrict})
+
+				if err != nil {
+					t.Fatalf("Round trip parser failed for version %d, issuer %s: %v\n%q", version, iin, err, data)
+				}
+
+				for _, field := range roundTripFields {
+					if want, got := field.field(expected), field.field(parsed); want != got {
+						t.Errorf("Round trip lost the %s for version %d, issuer %s: want %q, got %q", field.name, version, iin, want, got)
+					}
+				}
+
+				// The date format should always come from the country.
+				if version >= 3 && parsed.DateFormatRule() != DateFormatRuleCountry {
+					t.Errorf("Round trip date format ignored the country for version %d, issuer %s", version, iin)
+				}
+
+				if again, err := Encode(parsed, version); err != nil || again != data {
+					t.Errorf("Round trip encoder is not stable for version %d, issuer %s", version, iin)
+				}
+			}
+		}
+	}
+}
+
+func TestRoundTripLossy(t *testing.T) {
+	l := new(DLIDLicense)
+
+	l.SetIssuerId(ColoradoIssuerId)
+	l.SetCustomerId("T64235789")
+	l.SetLastName("PUBLIC")
+	l.SetFirstName("JOHN")
+	l.SetMiddleNames([]string{"Q"})
+	l.SetStreet("123 MAIN STREET")
+	l.SetCity("DENVER")
+	l.SetState("CO")
+	l.SetPostal("80202")
+	l.SetDateOfBirth(NewDate(time.Date(1976, 11, 23, 0, 0, 0, 0, time.UTC)))
+	l.SetExpiryDate(NewDate(time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)))
+	l.SetIssueDate(NewDate(time.Date(2016, 12, 1, 0, 0, 0, 0, time.UTC)))
+
+	// Colorado and Tennessee write version 1 names first, middle, last, but
+	// the encoder follows the standard.  Strict parsing gets the names back;
+	// Parse assumes the Colorado order and swaps them round.
+
+	data, err := Encode(l, 1)
+
+	if err != nil || !strings.Contains(data, "\nDAAPUBLIC,JOHN,Q\n") {
+		t.Fatal("Lossy round trip wrote wrong Colorado name order")
+	}
+
+	s, err := ParseWithOptions(data, ParseOptions{Mode: ParseModeStrict})
+
+	if err != nil || s.LastName() != "PUBLIC" || s.FirstName() != "JOHN" || s.MiddleNames()[0] != "Q" {
+		t.Error("Lossy round trip got wrong strict Colorado names")
+	}
+
+	s, err = Parse(data)
+
+	if err != nil || s.LastName() != "Q" || s.FirstName() != "PUBLIC" {
+		t.Error("Lossy round trip did not swap Colorado names")
+	}
+
+	// There's no code for a compliance type that couldn't be read, so it
+	// isn't written at all.
+
+	l.SetComplianceType(ComplianceTypeUnknown)
+
+	data, err = Encode(l, 10)
+
+	if err != nil || strings.Contains(data, "\nDDA") {
+		t.Error("Lossy round trip wrote unknown compliance type")
+	}
+
+	l.SetComplianceType(ComplianceTypeNone)
+
+	// Zips are padded to the width of the field: with spaces in versions 1
+	// and 2, with zeros to 11 digits in version 3 and to 9 digits from version
+	// 4.  Only the raw value shows the padding; the zip itself comes back.
+
+	l.SetIssuerId("636000")
+
+	padding := map[int]string{1: "80202      ", 2: "80202      ", 3: "80202000000", 4: "802020000", 10: "802020000"}
+
+	for version, raw := range padding {
+		data, err := Encode(l, version)
+
+		if err != nil {
+			t.Fatal("Lossy round trip encoder failed")
+		}
+
+		s, err := Parse(data)
+
+		if err != nil {
+			t.Fatal("Lossy round trip parser failed")
+		}
+
+		if value, _ := s.Element("DAK"); value != raw || s.Postal() != "80202" {
+			t.Errorf("Lossy round trip got wrong postal padding for version %d: %q", version, value)
+		}
+	}
+
+	// Names before version 4 are written as a single element, and the
+	// parser treats spaces in it as separators.
+
+	l.SetFirstName("MARY ANN")
+
+	for version := 1; version <= 10; version++ {
+		data, err := Encode(l, version)
+
+		if err != nil {
+			t.Fatal("Lossy round trip encoder failed")
+		}
+
+		s, err := Parse(data)
+
+		if err != nil {
+			t.Fatal("Lossy round trip parser failed")
+		}
+
+		if (s.FirstName() == "MARY ANN") != (version >= 4) {
+			t.Errorf("Lossy round trip got wrong first name for version %d: %q", version, s.FirstName())
+		}
+	}
+
+	// Dates that aren't dates are written as they were found.
+
+	l.SetFirstName("JOHN")
+	l.SetIssueDate(invalidDate("20161301"))
+
+	data, err = Encode(l, 10)
+
+	if err != nil || !strings.Contains(data, "\nDBD20161301") {
+		t.Error("Lossy round trip did not write invalid date as found")
+	}
+
+	if s, err := Parse(data); err != nil || !s.IssueDate().IsInvalid() || s.IssueDate().Raw() != "20161301" {
+		t.Error("Lossy round trip did not read back invalid date")
+	}
+}
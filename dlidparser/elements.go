@@ -0,0 +1,111 @@
+package dlidparser
+
+import (
+	"strings"
+)
+
+// Elements is an ordered collection of the data elements found in a subfile.
+// Each element is keyed by its three-character ID, and its value is kept
+// exactly as it appeared in the barcode, padding and all.  Elements that
+// appear more than once are all kept, but Value only returns the first.
+type Elements struct {
+	ids     []string
+	values  []string
+	offsets []int
+}
+
+func (e *Elements) add(id string, value string) {
+	e.addAt(id, value, -1)
+}
+
+// addAt adds an element that started at the given byte offset in its subfile,
+// so that errors can point at it.
+func (e *Elements) addAt(id string, value string, offset int) {
+	e.ids = append(e.ids, id)
+	e.values = append(e.values, value)
+	e.offsets = append(e.offsets, offset)
+}
+
+// Len returns the number of elements in the collection.
+func (e *Elements) Len() int {
+	if e == nil {
+		return 0
+	}
+
+	return len(e.ids)
+}
+
+// IDs returns the element IDs in the order that they appeared in the barcode.
+func (e *Elements) IDs() []string {
+	if e == nil {
+		return nil
+	}
+
+	return e.ids
+}
+
+// At returns the ID and value of the element at the given position.
+func (e *Elements) At(index int) (id string, value string) {
+	return e.ids[index], e.values[index]
+}
+
+// Value returns the raw value of the first element with the given ID, and
+// whether the element was present at all.
+func (e *Elements) Value(id string) (value string, ok bool) {
+	if e == nil {
+		return
+	}
+
+	for i := range e.ids {
+		if e.ids[i] == id {
+			return e.values[i], true
+		}
+	}
+
+	return
+}
+
+// offset returns the byte offset within its subfile of the first element with
+// the given ID, if it is known.
+func (e *Elements) offset(id string) (offset int, ok bool) {
+	if e == nil {
+		return
+	}
+
+	for i := range e.ids {
+		if e.ids[i] == id {
+			return e.offsets[i], e.offsets[i] > -1
+		}
+	}
+
+	return
+}
+
+func parseElements(subfileData string, subfileType string) *Elements {
+
+	elements := new(Elements)
+
+	position := 0
+
+	if strings.HasPrefix(subfileData, subfileType) {
+		subfileData = subfileData[len(subfileType):]
+		position = len(subfileType)
+	}
+
+	if index := strings.Index(subfileData, "\r"); index > -1 {
+		subfileData = subfileData[:index]
+	}
+
+	components := strings.Split(subfileData, "\n")
+
+	for component := range components {
+
+		if len(components[component]) >= 3 {
+			elements.addAt(components[component][0:3], components[component][3:], position)
+		}
+
+		position += len(components[component]) + 1
+	}
+
+	return elements
+}
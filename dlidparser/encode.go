@@ -0,0 +1,507 @@
+package dlidparser
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// These are the kinds of error that Encode can report, along with
+// ErrUnsupportedVersion.
+var (
+	ErrInvalidIssuer = errors.New("Licence does not have a valid issuer ID")
+	ErrDataTooLong   = errors.New("Licence data is too long to encode")
+
+	// ErrInvalidElement means that a value contains one of the separator or
+	// terminator characters that hold the barcode together.  Writing it would
+	// cut the element short and throw every subfile length out.
+	ErrInvalidElement = errors.New("Licence data contains a separator or terminator character")
+)
+
+// Encode turns a licence back into the contents of a PDF417 barcode, using
+// the given version of the standard.  It is the inverse of Parse: the header,
+// subfile directory and data elements follow the standard exactly, none of
+// the mistakes that Parse puts up with are reproduced, and anything that the
+// version has no element for is left out.  Jurisdiction-specific subfiles are
+// written after the licence data with their elements in ID order.
+func Encode(license *DLIDLicense, version int) (data string, err error) {
+
+	if version < 1 || version > 10 {
+		return "", ErrUnsupportedVersion
+	}
+
+	if len(license.IssuerId()) != 6 || !isDigits(license.IssuerId()) {
+		return "", ErrInvalidIssuer
+	}
+
+	licenceData, err := encodeElements(license, version)
+
+	if err != nil {
+		return "", err
+	}
+
+	type subfile struct {
+		subfileType string
+		data        string
+	}
+
+	var subfiles []subfile
+
+	switch license.DocumentType() {
+	case DocumentTypeIdentificationCard:
+		subfiles = append(subfiles, subfile{"ID", "ID" + licenceData})
+	case DocumentTypeDriverLicenseAndIdentificationCard:
+		subfiles = append(subfiles, subfile{"DL", "DL" + licenceData}, subfile{"ID", "ID" + licenceData})
+	default:
+		subfiles = append(subfiles, subfile{"DL", "DL" + licenceData})
+	}
+
+	for _, jurisdictionSubfile := range license.JurisdictionSubfiles() {
+		jurisdictionData, err := encodeJurisdictionElements(jurisdictionSubfile)
+
+		if err != nil {
+			return "", err
+		}
+
+		subfiles = append(subfiles, subfile{jurisdictionSubfile.Type(), jurisdictionSubfile.Type() + jurisdictionData})
+	}
+
+	if len(subfiles) > 99 {
+		return "", ErrDataTooLong
+	}
+
+	header := "@\n\x1e\rANSI " + license.IssuerId() + padNumber(version, 2)
+
+	// Everything after version 1 has a jurisdiction version number, which we
+	// have no way of knowing, so it is always zero.
+	if version > 1 {
+		header += "00"
+	}
+
+	header += padNumber(len(subfiles), 2)
+
+	offset := len(header) + len(subfiles)*subfileEntryLength
+
+	var directory strings.Builder
+	var body strings.Builder
+
+	for _, subfile := range subfiles {
+		if offset+len(subfile.data) > 9999 {
+			return "", ErrDataTooLong
+		}
+
+		directory.WriteString(subfile.subfileType + padNumber(offset, 4) + padNumber(len(subfile.data), 4))
+		body.WriteString(subfile.data)
+
+		offset += len(subfile.data)
+	}
+
+	return header + directory.String() + body.String(), nil
+}
+
+func encodeElements(license *DLIDLicense, version int) (string, error) {
+
+	elements := new(Elements)
+
+	add := func(id string, value string) {
+		if len(value) > 0 {
+			elements.add(id, value)
+		}
+	}
+
+	// Dates are always ISO in version 1 and always US in version 2.  After
+	// that they depend on the country, which is mandatory from version 3.
+
+	country := license.Country()
+	dateFormat := DateFormatCCYYMMDD
+
+	if version == 2 {
+		dateFormat = DateFormatMMDDCCYY
+	} else if version >= 3 {
+		if len(country) == 0 {
+			country = "USA"
+
+			if issuer, ok := license.Issuer(); ok && len(issuer.Country()) > 0 {
+				country = issuer.Country()
+			}
+		}
+
+		dateFormat = countryDateFormat(country)
+	}
+
+	date := func(id string, value Date) {
+		add(id, encodeDate(value, dateFormat))
+	}
+
+	add("DAQ", license.CustomerId())
+
+	if version == 1 {
+		add("DAR", license.VehicleClass())
+		add("DAS", license.RestrictionCodes())
+		add("DAT", license.EndorsementCodes())
+		add("DAA", encodeNameV1(license))
+		add("DAE", license.NameSuffix())
+	} else {
+		add("DCA", license.VehicleClass())
+		add("DCB", license.RestrictionCodes())
+		add("DCD", license.EndorsementCodes())
+		add("DCM", license.StandardVehicleClass())
+		add("DCN", license.StandardEndorsementCodes())
+		add("DCO", license.StandardRestrictionCodes())
+		add("DCS", license.LastName())
+	}
+
+	if version == 2 || version == 3 {
+		add("DCT", strings.Join(append([]string{license.FirstName()}, license.MiddleNames()...), ","))
+	}
+
+	if version >= 4 {
+		add("DAC", license.FirstName())
+		add("DAD", strings.Join(license.MiddleNames(), ","))
+		add("DCU", license.NameSuffix())
+		add("DDE", encodeTruncation(license.LastNameTruncation()))
+		add("DDF", encodeTruncation(license.FirstNameTruncation()))
+		add("DDG", encodeTruncation(license.MiddleNameTruncation()))
+	}
+
+	if version <= 3 {
+		add("DAF", license.NamePrefix())
+	}
+
+	if version >= 3 {
+		add("DCG", country)
+	}
+
+	mailing := license.MailingAddress()
+	residence := license.ResidenceAddress()
+
+	add("DAG", mailing.Street())
+	add("DAH", mailing.Street2())
+	add("DAI", mailing.City())
+	add("DAJ", mailing.State())
+	add("DAK", encodePostal(mailing.PostalCode(), version))
+	add("DAL", residence.Street())
+	add("DAM", residence.Street2())
+	add("DAN", residence.City())
+	add("DAO", residence.State())
+	add("DAP", encodePostal(residence.PostalCode(), version))
+
+	date("DBA", license.ExpiryDate())
+	date("DBB", license.DateOfBirth())
+	date("DBD", license.IssueDate())
+	add("DBC", encodeSex(license.Sex(), version))
+
+	if version == 1 {
+		add("DBK", license.SocialSecurityNumber())
+	}
+
+	add("DAU", encodeHeight(license.Height(), version))
+	add("DAV", encodeHeightV1Metric(license.Height(), version))
+	add("DAW", encodeWeight(license.Weight(), MeasurementUnitImperial))
+	add("DAX", encodeWeight(license.Weight(), MeasurementUnitMetric))
+	add("DAY", eyeColourCode(license.EyeColour()))
+	add("DAZ", hairColourCode(license.HairColour()))
+
+	if version >= 2 {
+		add("DCE", encodeWeightRange(license.WeightRange()))
+		add("DCF", license.DocumentDiscriminator())
+		add("DCK", license.InventoryControl())
+		add("DCJ", license.AuditInformation())
+		add("DCI", license.PlaceOfBirth())
+		add("DCL", raceCode(license.Race()))
+		add("DCH", license.FederalVehicleCodes())
+
+		for _, alias := range license.Aliases() {
+			add("DBN", alias.FamilyName())
+			add("DBG", alias.GivenName())
+			add("DBS", alias.Suffix())
+		}
+	}
+
+	if version >= 4 {
+		add("DDA", complianceTypeCode(license.ComplianceType()))
+		date("DDB", license.CardRevisionDate())
+		date("DDC", license.HazmatExpiryDate())
+		add("DDD", encodeFlag(license.LimitedDuration()))
+		add("DDK", encodeFlag(license.OrganDonor()))
+		add("DDL", encodeFlag(license.Veteran()))
+
+		// The getters fill these in from the date of birth, so use the
+		// fields to avoid writing dates that weren't on the card.
+		date("DDH", license.under18Until)
+		date("DDI", license.under19Until)
+		date("DDJ", license.under21Until)
+	}
+
+	return joinElements(elements)
+}
+
+func encodeJurisdictionElements(subfile *JurisdictionSubfile) (string, error) {
+
+	ids := make([]string, 0, len(subfile.Elements()))
+
+	for id := range subfile.Elements() {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	elements := new(Elements)
+
+	for _, id := range ids {
+		elements.add(id, subfile.Element(id))
+	}
+
+	return joinElements(elements)
+}
+
+func joinElements(elements *Elements) (string, error) {
+
+	var builder strings.Builder
+
+	for i := 0; i < elements.Len(); i++ {
+		id, value := elements.At(i)
+
+		if strings.ContainsAny(id+value, "\n\r\x1e") {
+			return "", ErrInvalidElement
+		}
+
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+
+		builder.WriteString(id + value)
+	}
+
+	builder.WriteString("\r")
+
+	return builder.String(), nil
+}
+
+func encodeNameV1(license *DLIDLicense) string {
+
+	// Colorado and Tennessee put the last name at the end, but that's their
+	// mistake and not one to copy.  Strict parsing reads this back correctly;
+	// Parse still assumes their order and swaps the names round.
+
+	names := append([]string{license.LastName(), license.FirstName()}, license.MiddleNames()...)
+
+	for len(names) > 0 && len(names[len(names)-1]) == 0 {
+		names = names[:len(names)-1]
+	}
+
+	return strings.Join(names, ",")
+}
+
+func encodeDate(date Date, format DateFormat) string {
+
+	if date.IsInvalid() {
+		return date.Raw()
+	}
+
+	if !date.IsPresent() {
+		return ""
+	}
+
+	year := padNumber(date.Year(), 4)
+	month := padNumber(int(date.Month()), 2)
+	day := padNumber(date.Day(), 2)
+
+	if format == DateFormatMMDDCCYY {
+		return month + day + year
+	}
+
+	return year + month + day
+}
+
+func encodePostal(postal PostalCode, version int) string {
+
+	// Versions 1 and 2 have an 11 character field padded with spaces.
+	// Version 3 pads US zips with zeros instead, and version 4 onwards cuts
+	// the field down to 9 characters.
+
+	width := 11
+
+	if version >= 4 {
+		width = 9
+	}
+
+	value := postal.Raw()
+
+	switch {
+	case postal.IsCanadian():
+		value = postal.FSA() + postal.LDU()
+	case postal.IsUS() && version >= 3:
+		plus4 := postal.Plus4()
+
+		if len(plus4) == 0 {
+			plus4 = "0000"
+		}
+
+		value = postal.ZIP5() + plus4
+
+		if version == 3 {
+			value += "00"
+		}
+	case postal.IsUS():
+		value = postal.ZIP5() + postal.Plus4()
+	}
+
+	if len(value) == 0 {
+		return ""
+	}
+
+	for len(value) < width {
+		value += " "
+	}
+
+	return value
+}
+
+func encodeSex(sex DriverSex, version int) string {
+	switch sex {
+	case DriverSexMale:
+		return "1"
+	case DriverSexFemale:
+		return "2"
+	case DriverSexNotSpecified:
+		if version >= 10 {
+			return "9"
+		}
+	}
+
+	return ""
+}
+
+func encodeHeight(height Height, version int) string {
+
+	if height.IsZero() {
+		return ""
+	}
+
+	// Version 1 has a separate element for metric heights, and writes
+	// imperial heights in feet and inches.
+
+	if version == 1 {
+		if height.Unit() != MeasurementUnitImperial {
+			return ""
+		}
+
+		return strconv.Itoa(height.Value()/12) + padNumber(height.Value()%12, 2)
+	}
+
+	if height.Unit() == MeasurementUnitMetric {
+		return padNumber(height.Value(), 3) + " cm"
+	}
+
+	return padNumber(height.Value(), 3) + " in"
+}
+
+func encodeHeightV1Metric(height Height, version int) string {
+
+	if version != 1 || height.Unit() != MeasurementUnitMetric {
+		return ""
+	}
+
+	return padNumber(height.Value(), 3)
+}
+
+func encodeWeight(weight Weight, unit MeasurementUnit) string {
+
+	if weight.IsZero() || weight.Unit() != unit {
+		return ""
+	}
+
+	return padNumber(weight.Value(), 3)
+}
+
+func encodeWeightRange(weightRange WeightRange) string {
+
+	if weightRange < WeightRange0 || weightRange > WeightRange9 {
+		return ""
+	}
+
+	return strconv.Itoa(int(weightRange - WeightRange0))
+}
+
+func encodeTruncation(truncation Truncation) string {
+	switch truncation {
+	case TruncationTruncated:
+		return "T"
+	case TruncationNotTruncated:
+		return "N"
+	case TruncationUnknown:
+		return "U"
+	}
+
+	return ""
+}
+
+func encodeFlag(b bool) string {
+	if b {
+		return "1"
+	}
+
+	return ""
+}
+
+func complianceTypeCode(complianceType ComplianceType) string {
+	switch complianceType {
+	case ComplianceTypeFullyCompliant:
+		return "F"
+	case ComplianceTypeMateriallyCompliant:
+		return "M"
+	case ComplianceTypeNonCompliant:
+		return "N"
+	}
+
+	// The standard has no code for a compliance type we couldn't read, so
+	// the element is left out.
+
+	return ""
+}
+
+// The code maps include the two-letter forms seen on version 1 cards, so
+// only the three-letter standard codes are written.
+
+func eyeColourCode(colour EyeColour) string {
+	for code, value := range eyeColourCodes {
+		if value == colour && len(code) == 3 {
+			return code
+		}
+	}
+
+	return ""
+}
+
+func hairColourCode(colour HairColour) string {
+	for code, value := range hairColourCodes {
+		if value == colour && len(code) == 3 {
+			return code
+		}
+	}
+
+	return ""
+}
+
+func raceCode(race Race) string {
+	for code, value := range raceCodes {
+		if value == race {
+			return code
+		}
+	}
+
+	return ""
+}
+
+func padNumber(value int, width int) string {
+
+	number := strconv.Itoa(value)
+
+	for len(number) < width {
+		number = "0" + number
+	}
+
+	return number
+}
@@ -0,0 +1,84 @@
+package dlidparser
+
+import (
+	"errors"
+)
+
+// These are the kinds of error that Parse can report.  Every error returned by
+// the parser is a *ParseError that wraps one of them, so callers can use
+// errors.Is to decide what to do without resorting to matching strings.  As a
+// rule of thumb, header and subfile range errors mean the scan was probably
+// bad and is worth repeating, whereas an unsupported version means the card
+// will never be readable by this package.
+var (
+	ErrHeader             = errors.New("Data does not contain expected header")
+	ErrUnsupportedVersion = errors.New("Unsupported DLID version number")
+	ErrSubfileRange       = errors.New("Data contains an invalid subfile range")
+	ErrMissingElement     = errors.New("Data is missing a mandatory element")
+	ErrMalformedDate      = errors.New("Data contains a malformed date")
+)
+
+// ParseError describes why some data could not be parsed.  It records the
+// version number and issuer from the header, if the parser got far enough to
+// read them, and the byte offset into the data at which the problem was
+// found.
+type ParseError struct {
+	kind    error
+	message string
+	version int
+	issuer  string
+	offset  int
+	element string
+}
+
+func newParseError(kind error, offset int, message string) *ParseError {
+	parseError := new(ParseError)
+	parseError.kind = kind
+	parseError.offset = offset
+	parseError.message = message
+
+	return parseError
+}
+
+func (e *ParseError) Error() string {
+	return e.message
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.kind
+}
+
+func (e *ParseError) Kind() error {
+	return e.kind
+}
+
+func (e *ParseError) Version() int {
+	return e.version
+}
+
+func (e *ParseError) Issuer() string {
+	return e.issuer
+}
+
+func (e *ParseError) Offset() int {
+	return e.offset
+}
+
+// Element returns the ID of the data element that caused the error, or an
+// empty string if the error was not caused by a specific element.
+func (e *ParseError) Element() string {
+	return e.element
+}
+
+// shiftParseError moves the offset of a ParseError found while parsing a
+// subfile so that it is relative to the start of the whole barcode.
+func shiftParseError(err error, offset int) error {
+
+	var parseError *ParseError
+
+	if errors.As(err, &parseError) {
+		parseError.offset += offset
+	}
+
+	return err
+}
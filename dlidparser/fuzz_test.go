@@ -0,0 +1,86 @@
+package dlidparser
+
+import (
+	"testing"
+)
+
+// These fuzz targets exist to prove that nothing in the package panics,
+// however mangled the input.  Run them with:
+//
+//     go test -fuzz=FuzzParse ./dlidparser
+//
+// Errors are fine; crashes are not.
+
+var fuzzSeeds = []string{
+	"",
+	"@\n\x1e\rANSI 636",
+	"@\n\x1e\rANSI 636000110002",
+	"@\n\x1e\rANSI 636000070002DL-0410282",
+	"@\n\x1e\rANSI 6360350101DL00290178DLDAACDL,SAMPLE,CARD\nDAQC34078360601\nDBA20120101\nDBB19600101\nHMK%>?84_MAD@I,GXHUEMBM,XCCBHUFE@HMG",
+	"@\n\x1e\rANSI 6360200102DL00390187ZV02260031DLDAQ0123456789ABC\nDAAJOHN,Q,PUBLIC\nDAG123 MAIN STREET\nDAIANYTOWN\nDAJVA\nDAK123459999  \nDARDM  \nDAS          \nDAT     \nDAU509\nDAW175\nDAYBL \nDAZBR \nDBA20011201\nDBB19761123\nDBCM\nDBD19961201\rZVZVAJURISDICTIONDEFINEDELEMENT\r",
+	"@\n\x1e\rANSI 636015030002DL00410217ZT02020022DLDCAB\nDCBLP\nDCDP\nDBA04052018\nDCSJONES\nDCTJAMES ROBERT R\nDBD07082012\nDBB10111978\nDBC1\nDAYBRO\nDAU 70 IN\nDAG123 SOME STREET\nDAICITY 12\nDAJTX\nDAK902100000  \nDAQ22334455\nDCF11111111111111111111\nDCGUSA\nDCHB   \nDAZBRO\nDCU\rZTZTA220\nZTBW\n",
+	"@\n\x1e\rANSI 636000070002DL00410282ZV03190008DLDAQT64235789\nDCSSAMPLE\nDDEN\nDACMICHAEL\nDDFN\nDADJOHN,BOB\nDDGN\nDCUJR\nDCAD\nDCBK\nDCDPH\nDBD06062008\nDBB06071986\nDBA12102012\nDBC1\nDAU068 in\nDAYBRO\nDAG2300 WEST BROAD STREET\nDAIRICHMOND\nDAJVA\nDAK232690000 \nDCF2424244747474786102204\nDCGUSA\nDCK123456789\nDDAM\nDDB06062008\nDDC06062009\nDDD1\rZVZVA01\r",
+	"@\n\x1e\rANSI 636000100002DL00410282ZV03190008DLDAQT64235789\nDCSSAMPLE\nDACMICHAEL\nDBB\nDBA1\nDBC9\nDAK2326\nDCGUSA\nDDAF\nDDD1\rZVZVA01\r",
+}
+
+var fuzzDataSeeds = []string{
+	"",
+	"DL",
+	"L",
+	"ID",
+	"DLDAA\nDBB\nDBA1\nDBD12\nDAK123456",
+	"DLDAQT64235789\nDCSSAMPLE\nDACMICHAEL\nDBB06071986\nDBA12102012\nDBD06062008\nDCGUSA\nDAK2326900\r",
+	"DLDAQT64235789\nDCSSAMPLE\nDACMICHAEL\nDBB19860607\nDBA20121210\nDBD20080606\nDCGCAN\nDAKA1B2C3\r",
+}
+
+func fuzzParseData(f *testing.F, parseData func(string, string) (*DLIDLicense, error)) {
+	for _, seed := range fuzzDataSeeds {
+		f.Add(seed, "636000")
+	}
+
+	f.Fuzz(func(t *testing.T, data string, issuer string) {
+		parseData(data, issuer)
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data string) {
+		license, err := Parse(data)
+
+		if err == nil && license == nil {
+			t.Error("Parse returned neither a licence nor an error")
+		}
+	})
+}
+
+func FuzzParseDataV1(f *testing.F) {
+	fuzzParseData(f, func(data string, issuer string) (*DLIDLicense, error) {
+		return parseDataV1(data, issuer, ParseOptions{})
+	})
+}
+
+func FuzzParseDataV2(f *testing.F) {
+	fuzzParseData(f, parseDataV2)
+}
+
+func FuzzParseDataV3(f *testing.F) {
+	fuzzParseData(f, func(data string, issuer string) (*DLIDLicense, error) {
+		return parseDataV3(data, issuer, ParseOptions{})
+	})
+}
+
+func FuzzParseDataV4(f *testing.F) {
+	fuzzParseData(f, func(data string, issuer string) (*DLIDLicense, error) {
+		return parseDataV4(data, issuer, 4, ParseOptions{})
+	})
+}
+
+func FuzzParseDataV10(f *testing.F) {
+	fuzzParseData(f, func(data string, issuer string) (*DLIDLicense, error) {
+		return parseDataV4(data, issuer, 10, ParseOptions{})
+	})
+}
@@ -1,71 +1,231 @@
 package dlidparser
 
-var issuers = map[string]string{
-	"636033": "Alabama",
-	"636059": "Alaska",
-	"604427": "American Samoa",
-	"636026": "Arizona",
-	"636021": "Arkansas",
-	"636028": "British Columbia",
-	"636014": "California",
-	"636056": "Coahuila",
-	"636020": "Colorado",
-	"636006": "Connecticut",
-	"636043": "District of Columbia",
-	"636011": "Delaware",
-	"636010": "Florida",
-	"636055": "Georgia",
-	"636019": "Guam",
-	"636047": "Hawaii",
-	"636057": "Hidalgo",
-	"636050": "Idaho",
-	"636035": "Illinois",
-	"636037": "Indiana",
-	"636018": "Iowa",
-	"636022": "Kansas",
-	"636046": "Kentucky",
-	"636007": "Louisiana",
-	"636041": "Maine",
-	"636048": "Manitoba",
-	"636003": "Maryland",
-	"636002": "Massachusetts",
-	"636032": "Michigan",
-	"636038": "Minnesota",
-	"636051": "Mississippi",
-	"636030": "Missouri",
-	"636008": "Montana",
-	"636054": "Nebraska",
-	"636049": "Nevada",
-	"636017": "New Brunswick",
-	"636039": "New Hampshire",
-	"636036": "New Jersey",
-	"636009": "New Mexico",
-	"636001": "New York",
-	"636016": "Newfoundland",
-	"636004": "North Carolina",
-	"636034": "North Dakota",
-	"636013": "Nova Scotia",
-	"636023": "Ohio",
-	"636058": "Oklahoma",
-	"636012": "Ontario",
-	"636029": "Oregon",
-	"636025": "Pennsylvania",
-	"604426": "Prince Edward Island",
-	"604428": "Quebec",
-	"636052": "Rhode Island",
-	"636044": "Saskatchewan",
-	"636005": "South Carolina",
-	"636042": "South Dakota",
-	"636053": "Tennessee",
-	"636027": "State Dept (USA)",
-	"636015": "Texas",
-	"636062": "US Virgin Islands",
-	"636040": "Utah",
-	"636024": "Vermont",
-	"636000": "Virginia",
-	"636045": "Washington",
-	"636061": "West Virginia",
-	"636031": "Wisconsin",
-	"636060": "Wyoming",
-	"604429": "Yukon",
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Issuer describes a jurisdiction that issues DL/ID cards.  The IIN is the
+// six digit issuer identification number at the start of every barcode, the
+// code is the two-letter postal abbreviation and the country is the same
+// three-letter code used in the DCG element.  The region is the AAMVA region
+// from 1 to 4, or 0 for issuers outside the AAMVA regions.
+type Issuer struct {
+	iin     string
+	name    string
+	code    string
+	country string
+	region  int
+}
+
+func NewIssuer(iin string, name string, code string, country string, region int) Issuer {
+	return Issuer{iin: iin, name: name, code: code, country: country, region: region}
+}
+
+func (i Issuer) IIN() string {
+	return i.iin
+}
+
+func (i Issuer) Name() string {
+	return i.name
+}
+
+func (i Issuer) Code() string {
+	return i.code
+}
+
+func (i Issuer) Country() string {
+	return i.country
+}
+
+func (i Issuer) Region() int {
+	return i.region
+}
+
+// The Mexican states don't have a two-letter abbreviation that anyone agrees
+// on, so their codes are left empty, as is the State Department's.  Coahuila
+// and Hidalgo are the only Mexican states with IINs on the AAMVA list that
+// this table was built from.  Any others that turn up in the wild should be
+// added with RegisterIssuer or LoadIssuersCSV once their IIN is confirmed,
+// rather than guessed at here.
+
+var defaultIssuers = []Issuer{
+	{"636033", "Alabama", "AL", "USA", 2},
+	{"636059", "Alaska", "AK", "USA", 4},
+	{"604432", "Alberta", "AB", "CAN", 4},
+	{"604427", "American Samoa", "AS", "USA", 4},
+	{"636026", "Arizona", "AZ", "USA", 4},
+	{"636021", "Arkansas", "AR", "USA", 2},
+	{"636028", "British Columbia", "BC", "CAN", 4},
+	{"636014", "California", "CA", "USA", 4},
+	{"636056", "Coahuila", "", "MEX", 0},
+	{"636020", "Colorado", "CO", "USA", 4},
+	{"636006", "Connecticut", "CT", "USA", 1},
+	{"636011", "Delaware", "DE", "USA", 1},
+	{"636043", "District of Columbia", "DC", "USA", 1},
+	{"636010", "Florida", "FL", "USA", 2},
+	{"636055", "Georgia", "GA", "USA", 2},
+	{"636019", "Guam", "GU", "USA", 4},
+	{"636047", "Hawaii", "HI", "USA", 4},
+	{"636057", "Hidalgo", "", "MEX", 0},
+	{"636050", "Idaho", "ID", "USA", 4},
+	{"636035", "Illinois", "IL", "USA", 3},
+	{"636037", "Indiana", "IN", "USA", 3},
+	{"636018", "Iowa", "IA", "USA", 3},
+	{"636022", "Kansas", "KS", "USA", 3},
+	{"636046", "Kentucky", "KY", "USA", 2},
+	{"636007", "Louisiana", "LA", "USA", 2},
+	{"636041", "Maine", "ME", "USA", 1},
+	{"636048", "Manitoba", "MB", "CAN", 3},
+	{"636003", "Maryland", "MD", "USA", 1},
+	{"636002", "Massachusetts", "MA", "USA", 1},
+	{"636032", "Michigan", "MI", "USA", 3},
+	{"636038", "Minnesota", "MN", "USA", 3},
+	{"636051", "Mississippi", "MS", "USA", 2},
+	{"636030", "Missouri", "MO", "USA", 3},
+	{"636008", "Montana", "MT", "USA", 4},
+	{"636054", "Nebraska", "NE", "USA", 3},
+	{"636049", "Nevada", "NV", "USA", 4},
+	{"636017", "New Brunswick", "NB", "CAN", 1},
+	{"636039", "New Hampshire", "NH", "USA", 1},
+	{"636036", "New Jersey", "NJ", "USA", 1},
+	{"636009", "New Mexico", "NM", "USA", 4},
+	{"636001", "New York", "NY", "USA", 1},
+	{"636016", "Newfoundland", "NL", "CAN", 1},
+	{"636004", "North Carolina", "NC", "USA", 2},
+	{"636034", "North Dakota", "ND", "USA", 3},
+	{"604430", "Northwest Territories", "NT", "CAN", 4},
+	{"636013", "Nova Scotia", "NS", "CAN", 1},
+	{"604433", "Nunavut", "NU", "CAN", 4},
+	{"636023", "Ohio", "OH", "USA", 3},
+	{"636058", "Oklahoma", "OK", "USA", 2},
+	{"636012", "Ontario", "ON", "CAN", 3},
+	{"636029", "Oregon", "OR", "USA", 4},
+	{"636025", "Pennsylvania", "PA", "USA", 1},
+	{"604426", "Prince Edward Island", "PE", "CAN", 1},
+	{"604431", "Puerto Rico", "PR", "USA", 2},
+	{"604428", "Quebec", "QC", "CAN", 1},
+	{"636052", "Rhode Island", "RI", "USA", 1},
+	{"636044", "Saskatchewan", "SK", "CAN", 3},
+	{"636005", "South Carolina", "SC", "USA", 2},
+	{"636042", "South Dakota", "SD", "USA", 3},
+	{"636027", "State Dept (USA)", "", "USA", 0},
+	{"636053", "Tennessee", "TN", "USA", 2},
+	{"636015", "Texas", "TX", "USA", 2},
+	{"636062", "US Virgin Islands", "VI", "USA", 2},
+	{"636040", "Utah", "UT", "USA", 4},
+	{"636024", "Vermont", "VT", "USA", 1},
+	{"636000", "Virginia", "VA", "USA", 2},
+	{"636045", "Washington", "WA", "USA", 4},
+	{"636061", "West Virginia", "WV", "USA", 2},
+	{"636031", "Wisconsin", "WI", "USA", 3},
+	{"636060", "Wyoming", "WY", "USA", 4},
+	{"604429", "Yukon", "YT", "CAN", 4},
+}
+
+var issuers = map[string]Issuer{}
+
+func init() {
+	for _, issuer := range defaultIssuers {
+		issuers[issuer.iin] = issuer
+	}
+}
+
+// LookupIssuer returns the issuer with the given IIN, and whether it is known.
+func LookupIssuer(iin string) (issuer Issuer, ok bool) {
+	issuer, ok = issuers[iin]
+	return
+}
+
+// RegisterIssuer adds an issuer to the registry, replacing any existing issuer
+// with the same IIN.  As with jurisdiction decoders, issuers should be
+// registered before any parsing starts.
+func RegisterIssuer(issuer Issuer) {
+	issuers[issuer.iin] = issuer
+}
+
+type issuerJSON struct {
+	IIN     string `json:"iin"`
+	Name    string `json:"name"`
+	Code    string `json:"code"`
+	Country string `json:"country"`
+	Region  int    `json:"region"`
+}
+
+// LoadIssuersJSON registers the issuers in a JSON array of objects with "iin",
+// "name", "code", "country" and "region" keys.  Nothing is registered if the
+// data can't be read.
+func LoadIssuersJSON(r io.Reader) error {
+
+	var entries []issuerJSON
+
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+
+	loaded := make([]Issuer, 0, len(entries))
+
+	for _, entry := range entries {
+		if len(entry.IIN) == 0 {
+			return errors.New("Issuer is missing its IIN")
+		}
+
+		loaded = append(loaded, NewIssuer(entry.IIN, entry.Name, entry.Code, entry.Country, entry.Region))
+	}
+
+	for _, issuer := range loaded {
+		RegisterIssuer(issuer)
+	}
+
+	return nil
+}
+
+// LoadIssuersCSV registers the issuers in CSV data with the columns iin, name,
+// code, country and region.  A header row starting with "iin" is skipped.
+// Nothing is registered if the data can't be read.
+func LoadIssuersCSV(r io.Reader) error {
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 5
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+
+	if err != nil {
+		return err
+	}
+
+	loaded := make([]Issuer, 0, len(records))
+
+	for index, record := range records {
+		if index == 0 && strings.EqualFold(record[0], "iin") {
+			continue
+		}
+
+		if len(record[0]) == 0 {
+			return errors.New("Issuer on line " + strconv.Itoa(index+1) + " is missing its IIN")
+		}
+
+		region := 0
+
+		if len(record[4]) > 0 {
+			region, err = strconv.Atoi(record[4])
+
+			if err != nil {
+				return errors.New("Issuer on line " + strconv.Itoa(index+1) + " has an invalid region")
+			}
+		}
+
+		loaded = append(loaded, NewIssuer(record[0], record[1], record[2], record[3], region))
+	}
+
+	for _, issuer := range loaded {
+		RegisterIssuer(issuer)
+	}
+
+	return nil
 }
@@ -0,0 +1,113 @@
+package dlidparser
+
+import (
+	"strings"
+)
+
+// JurisdictionSubfile holds the elements of a jurisdiction-specific subfile.
+// These subfiles use a "Z" designator followed by a letter chosen by the
+// jurisdiction (eg. "ZV" for Virginia), and each element ID is the designator
+// followed by another letter (eg. "ZVA").  The standard says nothing about
+// what the elements mean, so their values are kept as strings.  If a decoder
+// is registered for the issuer, it will also have turned any elements it
+// recognises into named fields.
+type JurisdictionSubfile struct {
+	subfileType string
+	elements    map[string]string
+	fields      map[string]string
+}
+
+func (j *JurisdictionSubfile) Type() string {
+	return j.subfileType
+}
+
+func (j *JurisdictionSubfile) Elements() map[string]string {
+	return j.elements
+}
+
+func (j *JurisdictionSubfile) Element(id string) string {
+	return j.elements[id]
+}
+
+func (j *JurisdictionSubfile) Fields() map[string]string {
+	return j.fields
+}
+
+func (j *JurisdictionSubfile) Field(name string) string {
+	return j.fields[name]
+}
+
+// JurisdictionDecoder converts the raw elements of a jurisdiction-specific
+// subfile into named fields.  It receives the elements keyed by element ID and
+// returns the fields keyed by name.  Elements that the decoder does not
+// recognise should simply be left out of the result.
+type JurisdictionDecoder func(elements map[string]string) map[string]string
+
+// No decoders are built in.  Jurisdictions don't publish what their elements
+// mean, and a guessed meaning is worse than none when the fields are used to
+// decide whether a card is genuine.  Callers who know what a jurisdiction's
+// elements are can register a decoder for it.
+var jurisdictionDecoders = map[string]JurisdictionDecoder{}
+
+// RegisterJurisdictionDecoder sets the decoder used for jurisdiction-specific
+// subfiles in barcodes from the given issuer, replacing any existing decoder.
+// Registering a nil decoder removes it.  Decoders should be registered before
+// any parsing starts; the registry is not safe for concurrent modification.
+func RegisterJurisdictionDecoder(issuer string, decoder JurisdictionDecoder) {
+	if decoder == nil {
+		delete(jurisdictionDecoders, issuer)
+		return
+	}
+
+	jurisdictionDecoders[issuer] = decoder
+}
+
+func parseJurisdictionSubfiles(subfiles []*Subfile, issuer string) (jurisdictionSubfiles []*JurisdictionSubfile) {
+
+	decoder := jurisdictionDecoders[issuer]
+
+	for _, subfile := range subfiles {
+		if !strings.HasPrefix(subfile.Type(), "Z") {
+			continue
+		}
+
+		jurisdictionSubfile := parseJurisdictionSubfile(subfile)
+
+		if decoder != nil {
+			jurisdictionSubfile.fields = decoder(jurisdictionSubfile.elements)
+		}
+
+		if jurisdictionSubfile.fields == nil {
+			jurisdictionSubfile.fields = map[string]string{}
+		}
+
+		jurisdictionSubfiles = append(jurisdictionSubfiles, jurisdictionSubfile)
+	}
+
+	return
+}
+
+func parseJurisdictionSubfile(subfile *Subfile) *JurisdictionSubfile {
+
+	jurisdictionSubfile := new(JurisdictionSubfile)
+	jurisdictionSubfile.subfileType = subfile.Type()
+	jurisdictionSubfile.elements = map[string]string{}
+
+	for _, identifier := range subfile.Elements().IDs() {
+
+		// Every element in the subfile should share its designator.  Anything
+		// else is junk picked up from a badly-located subfile.
+		if !strings.HasPrefix(identifier, subfile.Type()) {
+			continue
+		}
+
+		if _, ok := jurisdictionSubfile.elements[identifier]; ok {
+			continue
+		}
+
+		value, _ := subfile.Elements().Value(identifier)
+		jurisdictionSubfile.elements[identifier] = strings.Trim(value, " ")
+	}
+
+	return jurisdictionSubfile
+}
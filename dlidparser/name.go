@@ -0,0 +1,81 @@
+package dlidparser
+
+import (
+	"strings"
+)
+
+// Truncation says whether a name was cut short to fit on the card.  Version 4
+// onwards flags each part of the name separately.  Anything truncated will
+// only match a complete name by prefix.
+type Truncation int
+
+const (
+	TruncationNone Truncation = iota
+	TruncationTruncated
+	TruncationNotTruncated
+	TruncationUnknown
+)
+
+func parseTruncation(data string) Truncation {
+	switch strings.ToUpper(data) {
+	case "T":
+		return TruncationTruncated
+	case "N":
+		return TruncationNotTruncated
+	case "U":
+		return TruncationUnknown
+	}
+
+	return TruncationNone
+}
+
+// Alias is an "also known as" name recorded on the card.
+type Alias struct {
+	familyName string
+	givenName  string
+	suffix     string
+}
+
+func NewAlias(familyName string, givenName string, suffix string) Alias {
+	return Alias{familyName: familyName, givenName: givenName, suffix: suffix}
+}
+
+func (a Alias) FamilyName() string {
+	return a.familyName
+}
+
+func (a Alias) GivenName() string {
+	return a.givenName
+}
+
+func (a Alias) Suffix() string {
+	return a.suffix
+}
+
+// The alias elements aren't grouped in any way, so a second alias can only be
+// spotted by one of its parts turning up again.  Each element therefore fills
+// in the most recent alias unless that part of it has already been set.
+
+func (d *DLIDLicense) addAliasFamilyName(s string) {
+	d.setAliasPart(func(a *Alias) *string { return &a.familyName }, s)
+}
+
+func (d *DLIDLicense) addAliasGivenName(s string) {
+	d.setAliasPart(func(a *Alias) *string { return &a.givenName }, s)
+}
+
+func (d *DLIDLicense) addAliasSuffix(s string) {
+	d.setAliasPart(func(a *Alias) *string { return &a.suffix }, s)
+}
+
+func (d *DLIDLicense) setAliasPart(part func(a *Alias) *string, s string) {
+	if len(s) == 0 {
+		return
+	}
+
+	if len(d.aliases) == 0 || len(*part(&d.aliases[len(d.aliases)-1])) > 0 {
+		d.aliases = append(d.aliases, Alias{})
+	}
+
+	*part(&d.aliases[len(d.aliases)-1]) = s
+}
@@ -0,0 +1,163 @@
+package dlidparser
+
+// ParseMode controls how forgiving the parser is of data that does not follow
+// the standard.
+type ParseMode int
+
+const (
+
+	// ParseModeDefault tolerates the mistakes that real jurisdictions are known
+	// to make and rejects everything else.  This is how Parse behaves.
+	ParseModeDefault ParseMode = iota
+
+	// ParseModeStrict enforces the standard exactly.  Data that would need a
+	// workaround for a badly-implemented jurisdiction is either rejected (a
+	// wrong header, a broken or misplaced subfile directory entry) or read
+	// exactly as the standard says (names in version 1 are always
+	// comma-separated and in last, first, middle order, and the residence
+	// address never stands in for the mailing address).  Mandatory elements
+	// must be present and well-formed.
+	ParseModeStrict
+
+	// ParseModeLenient recovers as much as it can from broken data: subfile
+	// ranges that overrun the data are clamped, a missing subfile header is
+	// ignored and the header itself is only loosely checked.
+	ParseModeLenient
+)
+
+type ParseOptions struct {
+	Mode ParseMode
+}
+
+func licencePayload(data string, start int, end int, options ParseOptions) (payload string, warnings []Warning, err error) {
+
+	if options.Mode == ParseModeLenient && end > len(data) {
+		end = len(data)
+		warnings = append(warnings, newWarning(WarningSubfileRange, "", "Subfile range exceeds the length of the data"))
+	}
+
+	// Strictly speaking the licence data could run right up to the end of the
+	// barcode, but the parser has always insisted on there being something
+	// after it.  A subfile that finishes with its segment terminator is
+	// plainly complete, though, so that's allowed to end the barcode.  Lenient
+	// parsing doesn't care either way.
+
+	limit := len(data) - 1
+
+	if options.Mode == ParseModeLenient || (end == len(data) && end > start && data[end-1] == '\r') {
+		limit = len(data)
+	}
+
+	if start < 0 || end < start || end > limit {
+		err = newParseError(ErrSubfileRange, start, "Payload location does not exist in data")
+		return
+	}
+
+	payload = data[start:end]
+
+	if licenceDataDocumentType(payload) == DocumentTypeNone && options.Mode == ParseModeStrict {
+		err = newParseError(ErrSubfileRange, start, "Missing header in licence data chunk")
+		return
+	}
+
+	return
+}
+
+func lenientLicenceData(payload string, options ParseOptions) (string, []Warning) {
+
+	// If the subfile header is missing we assume that the directory pointed at
+	// the right place and the jurisdiction just forgot to label it.
+
+	if options.Mode == ParseModeLenient && licenceDataDocumentType(payload) == DocumentTypeNone {
+		return "DL" + payload, []Warning{newWarning(WarningSubfileHeader, "", "Licence data is missing its subfile header")}
+	}
+
+	return payload, nil
+}
+
+func validateStrict(license *DLIDLicense, version int) (err error) {
+
+	offset := 0
+
+	// The parsers replace the licence subfile's elements with their own, so
+	// we go back to the subfile data to find where each element was.  Strict
+	// parsing never relocates a subfile, so its offset is the real one.
+
+	var elements *Elements
+
+	if subfile := licenceSubfile(license.Subfiles()); subfile != nil {
+		offset = subfile.Offset()
+		elements = parseElements(subfile.Data(), subfile.Type())
+	}
+
+	lastNameElement := "DCS"
+
+	if version == 1 {
+		lastNameElement = "DAA"
+	}
+
+	missing := func(element string) error {
+		parseError := newParseError(ErrMissingElement, offset, "Data is missing mandatory element "+element)
+		parseError.element = element
+		return parseError
+	}
+
+	if len(license.CustomerId()) == 0 {
+		return missing("DAQ")
+	}
+
+	if len(license.LastName()) == 0 {
+		return missing(lastNameElement)
+	}
+
+	if version >= 3 && len(license.Country()) == 0 {
+		return missing("DCG")
+	}
+
+	dates := []struct {
+		element string
+		date    Date
+	}{
+		{"DBB", license.DateOfBirth()},
+		{"DBA", license.ExpiryDate()},
+		{"DBD", license.IssueDate()},
+	}
+
+	for _, date := range dates {
+		if date.date.IsAbsent() {
+			return missing(date.element)
+		}
+
+		if date.date.IsInvalid() {
+			dateOffset := offset
+
+			if elementOffset, ok := elements.offset(date.element); ok {
+				dateOffset += elementOffset
+			}
+
+			parseError := newParseError(ErrMalformedDate, dateOffset, "Data contains malformed date in element "+date.element)
+			parseError.element = date.element
+			return parseError
+		}
+	}
+
+	mailing := license.MailingAddress()
+
+	addressElements := []struct {
+		element string
+		value   string
+	}{
+		{"DAG", mailing.Street()},
+		{"DAI", mailing.City()},
+		{"DAJ", mailing.State()},
+		{"DAK", mailing.Postal()},
+	}
+
+	for _, addressElement := range addressElements {
+		if len(addressElement.value) == 0 {
+			return missing(addressElement.element)
+		}
+	}
+
+	return
+}
@@ -1,10 +1,7 @@
 package dlidparser
 
 import (
-	"errors"
-	"strconv"
 	"strings"
-	"time"
 )
 
 const ColoradoIssuerId string = "636020"
@@ -14,61 +11,57 @@ const MassachusettsIssuerId string = "636002"
 const SouthCarolinaIssuerId string = "636005"
 const TennesseeIssuerId string = "636053"
 
-func parseV1(data string, issuer string) (license *DLIDLicense, err error) {
+func parseV1(data string, issuer string, options ParseOptions) (license *DLIDLicense, err error) {
 
-	start, end, err := dataRangeV1(data)
+	subfiles, warnings, err := subfileDirectoryV1(data, options)
 
-	if issuer == IllinoisIssuerId {
+	if err != nil {
+		return
+	}
+
+	start, end := dataRange(licenceSubfile(subfiles))
+
+	if issuer == IllinoisIssuerId && options.Mode != ParseModeStrict {
 
 		// Illinois are the worst offenders so far in terms of mangling the DLID
 		// spec.  They store name, licence number, expiry date and date of birth
 		// as expected, but then go all-out crazy and encrypt everything else.
 		// This means that the data range exceeds the size of the licence data
 		// string.  We have to treat Illinois as a special case.
-		end = len(data) - 1
-	}
-
-	if end >= len(data) {
-		err = errors.New("Payload location does not exist in data")
-	}
-
-	payload := data[start:end]
+		if end > len(data)-1 {
+			warnings = append(warnings, newWarning(WarningSubfileRange, "", "Illinois subfile range exceeds the length of the data"))
+		}
 
-	if err != nil {
-		return
+		end = len(data) - 1
 	}
 
-	license, err = parseDataV1(payload, issuer)
+	payload, payloadWarnings, err := licencePayload(data, start, end, options)
 
 	if err != nil {
 		return
 	}
 
-	return
-}
+	warnings = append(warnings, payloadWarnings...)
 
-func dataRangeV1(data string) (start int, end int, err error) {
-
-	start, err = strconv.Atoi(data[21:25])
+	license, err = parseDataV1(payload, issuer, options)
 
 	if err != nil {
-		err = errors.New("Data contains malformed payload location")
+		err = shiftParseError(err, start)
 		return
 	}
 
-	end, err = strconv.Atoi(data[25:29])
+	licenceSubfile(subfiles).elements = license.elements
+	license.SetSubfiles(subfiles)
+	license.warnings = append(warnings, license.warnings...)
 
-	if err != nil {
-		err = errors.New("Data contains malformed payload length")
-		return
+	if documentType := subfileDocumentType(subfiles); documentType != DocumentTypeNone {
+		license.SetDocumentType(documentType)
 	}
 
-	end += start
-
 	return
 }
 
-func parseDataV1(licenceData string, issuer string) (license *DLIDLicense, err error) {
+func parseDataV1(licenceData string, issuer string, options ParseOptions) (license *DLIDLicense, err error) {
 
 	// Version 1 of the DLID card spec was published in 2000.  As of 2012, it is
 	// the version used in Colorado.
@@ -76,11 +69,22 @@ func parseDataV1(licenceData string, issuer string) (license *DLIDLicense, err e
 	// We want to strip off the "DL" chunk identifier, but every other state has
 	// managed to screw this up too.  Rather than handle this on a
 	// state-by-state basis, we'll check to see what's at the target location
-	// and handle it appropriately.
+	// and handle it appropriately.  Strict parsing doesn't handle it at all.
+	// The same goes for the rest of the workarounds in here.
 
-	if strings.HasPrefix(licenceData, "DL") {
+	strict := options.Mode == ParseModeStrict
+
+	var warnings []Warning
+
+	documentType := licenceDataDocumentType(licenceData)
+
+	if documentType == DocumentTypeNone && strict {
+		err = newParseError(ErrSubfileRange, 0, "Missing header in licence data chunk")
+		return
+	} else if documentType != DocumentTypeNone {
 
-		// POMG!  They actually got it right!
+		// POMG!  They actually got it right!  Identification cards use "ID"
+		// instead of "DL".
 		licenceData = licenceData[2:]
 	} else if strings.HasPrefix(licenceData, "L") {
 
@@ -88,6 +92,7 @@ func parseDataV1(licenceData string, issuer string) (license *DLIDLicense, err e
 		// the "DL" header part of the licence data.  In either case, their
 		// offset is off by one.
 		licenceData = licenceData[1:]
+		warnings = append(warnings, newWarning(WarningSubfileOffset, "", "Licence data offset is off by one"))
 	} else {
 
 		// Honestly, the spec really isn't that hard to follow.  I have no idea
@@ -96,19 +101,30 @@ func parseDataV1(licenceData string, issuer string) (license *DLIDLicense, err e
 		// at least some of their licenses.
 		//
 		// This else block is here just so I can grumble about badly-implemented
-		// specs.
+		// specs.  And record a warning.
+		warnings = append(warnings, newWarning(WarningSubfileHeader, "", "Licence data is missing its subfile header"))
+	}
+
+	// The segment terminator isn't part of the last element.
+	if index := strings.Index(licenceData, "\r"); index > -1 {
+		licenceData = licenceData[:index]
 	}
 
 	components := strings.Split(licenceData, "\n")
 
 	license = new(DLIDLicense)
 
+	license.warnings = warnings
+
+	license.SetDocumentType(documentType)
 	license.SetIssuerId(issuer)
-	license.SetIssuerName(issuers[issuer])
+	license.SetIssuerName(issuers[issuer].Name())
 
 	// Country is always USA for V1 licenses
 	license.SetCountry("USA")
 
+	license.elements = new(Elements)
+
 	for component := range components {
 
 		if len(components[component]) < 3 {
@@ -118,6 +134,8 @@ func parseDataV1(licenceData string, issuer string) (license *DLIDLicense, err e
 		identifier := components[component][0:3]
 		data := components[component][3:]
 
+		license.elements.add(identifier, data)
+
 		data = strings.Trim(data, " ")
 
 		switch identifier {
@@ -137,8 +155,10 @@ func parseDataV1(licenceData string, issuer string) (license *DLIDLicense, err e
 
 			separator := " "
 
-			if strings.Index(data, separator) == -1 {
+			if strict || strings.Index(data, separator) == -1 {
 				separator = ","
+			} else {
+				license.addWarning(WarningNameDelimiter, identifier, "Names are separated with spaces instead of commas")
 			}
 
 			names := strings.Split(data, separator)
@@ -151,9 +171,10 @@ func parseDataV1(licenceData string, issuer string) (license *DLIDLicense, err e
 			//
 			// http://www.aamva.org/IIN-and-RID/
 
-			if issuer == ColoradoIssuerId || issuer == TennesseeIssuerId {
+			if !strict && (issuer == ColoradoIssuerId || issuer == TennesseeIssuerId) {
 
 				// Colorado's backwards formatting style...
+				license.addWarning(WarningNameOrder, identifier, "Names are ordered first, middle, last")
 				license.SetFirstName(names[0])
 
 				if len(names) > 2 {
@@ -179,35 +200,32 @@ func parseDataV1(licenceData string, issuer string) (license *DLIDLicense, err e
 		case "DAE":
 			license.SetNameSuffix(data)
 
-		case "DAL":
-
-			// Colorado screws up again: they omit the *required* DAG field and
-			// substitute the optional DAL field in older licences.
-			fallthrough
-
 		case "DAG":
-			license.SetStreet(data)
-
-		case "DAN":
+			license.mailingAddress.SetStreet(data)
 
-			// Again, old Colorado licences ignore the spec.
-			fallthrough
+		case "DAH":
+			license.mailingAddress.SetStreet2(data)
 
 		case "DAI":
-			license.SetCity(data)
+			license.mailingAddress.SetCity(data)
 
-		case "DAO":
+		case "DAJ":
+			license.mailingAddress.SetState(data)
 
-			// Colorado strikes again.  Honestly, what is the point in having a
-			// spec if you don't follow it?
-			fallthrough
+		case "DAL":
+			license.residenceAddress.SetStreet(data)
 
-		case "DAJ":
-			license.SetState(data)
+		case "DAM":
+			license.residenceAddress.SetStreet2(data)
+
+		case "DAN":
+			license.residenceAddress.SetCity(data)
+
+		case "DAO":
+			license.residenceAddress.SetState(data)
 
 		case "DAP":
-			// More Colorado shenanigans.
-			fallthrough
+			license.residenceAddress.SetPostal(strings.Trim(data, " "))
 
 		case "DAK":
 
@@ -217,12 +235,13 @@ func parseDataV1(licenceData string, issuer string) (license *DLIDLicense, err e
 			// plus the +4 extension separated by "-".  The zip is
 			// apparently never written like that and always uses "+" as a
 			// separator.  Who knows what other states managed to
-			// accomplish.  At this point your dedicated programmer admits
-			// defeat in trying to untangle the incredible mess implemented
-			// in this single field; we'll just show the zip as it is
-			// stored.
+			// accomplish.  ParsePostalCode untangles the ones we know
+			// about and keeps anything else as it is stored.
 			license.SetPostal(strings.Trim(data, " "))
 
+		case "DAF":
+			license.SetNamePrefix(data)
+
 		case "DAQ":
 			license.SetCustomerId(data)
 
@@ -260,33 +279,63 @@ func parseDataV1(licenceData string, issuer string) (license *DLIDLicense, err e
 
 			// Optional and probably not available
 			license.SetSocialSecurityNumber(data)
+
+		case "DAU":
+			license.SetHeight(parseHeight(data, true))
+
+		case "DAV":
+			license.SetHeight(parseHeight(data+"cm", false))
+
+		case "DAW":
+			license.SetWeight(parseWeight(data, MeasurementUnitImperial))
+
+		case "DAX":
+			license.SetWeight(parseWeight(data, MeasurementUnitMetric))
+
+		case "DAY":
+			license.SetEyeColour(parseEyeColour(data))
+
+		case "DAZ":
+			license.SetHairColour(parseHairColour(data))
+
+		default:
+			license.addWarning(WarningUnknownElement, identifier, "Element is not handled by the parser")
 		}
 	}
 
-	return
-}
+	license.dateFormat = DateFormatCCYYMMDD
+	license.dateFormatRule = DateFormatRuleVersion
 
-func parseDateV1(data string) time.Time {
+	// Colorado screws up again: they omit the *required* mailing address
+	// fields and substitute the optional residence address fields in older
+	// licences.  Honestly, what is the point in having a spec if you don't
+	// follow it?  If there's no mailing address at all we'll use the
+	// residence address instead.  A partial mailing address is left alone;
+	// mixing the two would give an address that doesn't exist.
 
-	year, err := strconv.Atoi(data[:4])
-
-	if err != nil {
-		return time.Unix(0, 0)
+	if strict {
+		return
 	}
 
-	month, err := strconv.Atoi(data[4:6])
+	mailing := license.mailingAddress
 
-	if err != nil {
-		return time.Unix(0, 0)
+	if len(mailing.street) == 0 && len(mailing.city) == 0 && len(mailing.state) == 0 && mailing.postal.IsZero() && !license.residenceAddress.IsZero() {
+		license.mailingAddress = license.residenceAddress
+		license.addWarning(WarningResidenceAddress, "DAL", "Residence address used in place of mailing address")
 	}
 
-	day, err := strconv.Atoi(data[6:8])
+	return
+}
 
-	if err != nil {
-		return time.Unix(0, 0)
+func parseDateV1(data string) Date {
+
+	if len(data) == 0 {
+		return Date{}
 	}
 
-	location, err := time.LoadLocation("UTC")
+	if len(data) < 8 {
+		return invalidDate(data)
+	}
 
-	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, location)
+	return dateFromParts(data, data[:4], data[4:6], data[6:8])
 }
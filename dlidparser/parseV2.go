@@ -1,53 +1,45 @@
 package dlidparser
 
 import (
-	"errors"
-	"strconv"
 	"strings"
-	"time"
 )
 
-func parseV2(data string, issuer string) (license *DLIDLicense, err error) {
+func parseV2(data string, issuer string, options ParseOptions) (license *DLIDLicense, err error) {
 
-	start, end, err := dataRangeV2(data)
-
-	if end >= len(data) {
-		err = errors.New("Payload location does not exist in data")
-	}
-
-	payload := data[start:end]
+	subfiles, warnings, err := subfileDirectoryV2(data, options)
 
 	if err != nil {
 		return
 	}
 
-	license, err = parseDataV2(payload, issuer)
+	start, end := dataRange(licenceSubfile(subfiles))
+
+	payload, payloadWarnings, err := licencePayload(data, start, end, options)
 
 	if err != nil {
 		return
 	}
 
-	return
-}
+	warnings = append(warnings, payloadWarnings...)
 
-func dataRangeV2(data string) (start int, end int, err error) {
+	payload, payloadWarnings = lenientLicenceData(payload, options)
+	warnings = append(warnings, payloadWarnings...)
 
-	start, err = strconv.Atoi(data[23:27])
+	license, err = parseDataV2(payload, issuer)
 
 	if err != nil {
-		err = errors.New("Data contains malformed payload location")
+		err = shiftParseError(err, start)
 		return
 	}
 
-	end, err = strconv.Atoi(data[27:31])
+	licenceSubfile(subfiles).elements = license.elements
+	license.SetSubfiles(subfiles)
+	license.warnings = append(warnings, license.warnings...)
 
-	if err != nil {
-		err = errors.New("Data contains malformed payload length")
-		return
+	if documentType := subfileDocumentType(subfiles); documentType != DocumentTypeNone {
+		license.SetDocumentType(documentType)
 	}
 
-	end += start
-
 	return
 }
 
@@ -55,19 +47,31 @@ func parseDataV2(licenceData string, issuer string) (license *DLIDLicense, err e
 
 	// Version 1 of the DLID card spec was published in 2003.
 
-	if !strings.HasPrefix(licenceData, "DL") {
-		err = errors.New("Missing header in licence data chunk")
+	// Identification cards use an "ID" header instead of "DL" but are
+	// otherwise identical.
+
+	documentType := licenceDataDocumentType(licenceData)
+
+	if documentType == DocumentTypeNone {
+		err = newParseError(ErrSubfileRange, 0, "Missing header in licence data chunk")
 		return
 	}
 
 	licenceData = licenceData[2:]
 
+	if index := strings.Index(licenceData, "\r"); index > -1 {
+		licenceData = licenceData[:index]
+	}
+
 	components := strings.Split(licenceData, "\n")
 
 	license = new(DLIDLicense)
 
+	license.SetDocumentType(documentType)
 	license.SetIssuerId(issuer)
-	license.SetIssuerName(issuers[issuer])
+	license.SetIssuerName(issuers[issuer].Name())
+
+	license.elements = new(Elements)
 
 	for component := range components {
 
@@ -78,6 +82,8 @@ func parseDataV2(licenceData string, issuer string) (license *DLIDLicense, err e
 		identifier := components[component][0:3]
 		data := components[component][3:]
 
+		license.elements.add(identifier, data)
+
 		data = strings.Trim(data, " ")
 
 		switch identifier {
@@ -90,6 +96,15 @@ func parseDataV2(licenceData string, issuer string) (license *DLIDLicense, err e
 		case "DCD":
 			license.SetEndorsementCodes(data)
 
+		case "DCM":
+			license.SetStandardVehicleClass(data)
+
+		case "DCN":
+			license.SetStandardEndorsementCodes(data)
+
+		case "DCO":
+			license.SetStandardRestrictionCodes(data)
+
 		case "DCS":
 			license.SetLastName(data)
 
@@ -127,9 +142,60 @@ func parseDataV2(licenceData string, issuer string) (license *DLIDLicense, err e
 		case "DAK":
 			license.SetPostal(data)
 
+		case "DAH":
+			license.mailingAddress.SetStreet2(data)
+
+		case "DAL":
+			license.residenceAddress.SetStreet(data)
+
+		case "DAM":
+			license.residenceAddress.SetStreet2(data)
+
+		case "DAN":
+			license.residenceAddress.SetCity(data)
+
+		case "DAO":
+			license.residenceAddress.SetState(data)
+
+		case "DAP":
+			license.residenceAddress.SetPostal(data)
+
+		case "DAF":
+			license.SetNamePrefix(data)
+
+		case "DCI":
+			license.SetPlaceOfBirth(data)
+
+		case "DCL":
+			license.SetRace(parseRace(data))
+
+		case "DCH":
+			license.SetFederalVehicleCodes(data)
+
 		case "DAQ":
 			license.SetCustomerId(data)
 
+		case "DCF":
+			license.SetDocumentDiscriminator(data)
+
+		case "DCK":
+			license.SetInventoryControl(data)
+
+		case "DCJ":
+			license.SetAuditInformation(data)
+
+		case "DBN":
+			license.addAliasFamilyName(data)
+
+		case "DBG":
+			license.addAliasGivenName(data)
+
+		case "DBS":
+			license.addAliasSuffix(data)
+
+		case "DBA":
+			license.SetExpiryDate(parseDateV2(data))
+
 		case "DBB":
 			license.SetDateOfBirth(parseDateV2(data))
 
@@ -147,37 +213,52 @@ func parseDataV2(licenceData string, issuer string) (license *DLIDLicense, err e
 			default:
 				license.SetSex(DriverSexNone)
 			}
+
+		case "DBD":
+			license.SetIssueDate(parseDateV2(data))
+
+		case "DAU":
+			license.SetHeight(parseHeight(data, false))
+
+		case "DAW":
+			license.SetWeight(parseWeight(data, MeasurementUnitImperial))
+
+		case "DAX":
+			license.SetWeight(parseWeight(data, MeasurementUnitMetric))
+
+		case "DCE":
+			license.SetWeightRange(parseWeightRange(data))
+
+		case "DAY":
+			license.SetEyeColour(parseEyeColour(data))
+
+		case "DAZ":
+			license.SetHairColour(parseHairColour(data))
+
+		default:
+			license.addWarning(WarningUnknownElement, identifier, "Element is not handled by the parser")
 		}
 	}
 
+	license.dateFormat = DateFormatMMDDCCYY
+	license.dateFormatRule = DateFormatRuleVersion
+
 	return
 }
 
-func parseDateV2(data string) time.Time {
+func parseDateV2(data string) Date {
 
 	// Sooo, let me get this straight.  They switched from a reasonably-standard
 	// and universal date format (yyyyMMdd) to the bizarre US lumpy format
 	// (MMddyyyy)?  What were they thinking!?
 
-	month, err := strconv.Atoi(data[:2])
-
-	if err != nil {
-		return time.Unix(0, 0)
+	if len(data) == 0 {
+		return Date{}
 	}
 
-	day, err := strconv.Atoi(data[2:4])
-
-	if err != nil {
-		return time.Unix(0, 0)
-	}
-
-	year, err := strconv.Atoi(data[4:8])
-
-	if err != nil {
-		return time.Unix(0, 0)
+	if len(data) < 8 {
+		return invalidDate(data)
 	}
 
-	location, err := time.LoadLocation("UTC")
-
-	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, location)
+	return dateFromParts(data, data[4:8], data[:2], data[2:4])
 }
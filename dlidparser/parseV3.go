@@ -1,58 +1,83 @@
 package dlidparser
 
 import (
-	"errors"
-	"strconv"
 	"strings"
-	"time"
 )
 
-func parseV3(data string, issuer string) (license *DLIDLicense, err error) {
+func parseV3(data string, issuer string, options ParseOptions) (license *DLIDLicense, err error) {
 
-	start, end, err := dataRangeV2(data)
+	subfiles, warnings, err := subfileDirectoryV2(data, options)
 
-	if end >= len(data) {
-		err = errors.New("Payload location does not exist in data")
+	if err != nil {
+		return
 	}
 
-	payload := data[start:end]
+	start, end := dataRange(licenceSubfile(subfiles))
+
+	payload, payloadWarnings, err := licencePayload(data, start, end, options)
 
 	if err != nil {
 		return
 	}
 
-	license, err = parseDataV3(payload, issuer)
+	warnings = append(warnings, payloadWarnings...)
+
+	payload, payloadWarnings = lenientLicenceData(payload, options)
+	warnings = append(warnings, payloadWarnings...)
+
+	license, err = parseDataV3(payload, issuer, options)
 
 	if err != nil {
+		err = shiftParseError(err, start)
 		return
 	}
 
+	licenceSubfile(subfiles).elements = license.elements
+	license.SetSubfiles(subfiles)
+	license.warnings = append(warnings, license.warnings...)
+
+	if documentType := subfileDocumentType(subfiles); documentType != DocumentTypeNone {
+		license.SetDocumentType(documentType)
+	}
+
 	return
 }
 
-func parseDataV3(licenceData string, issuer string) (license *DLIDLicense, err error) {
+func parseDataV3(licenceData string, issuer string, options ParseOptions) (license *DLIDLicense, err error) {
 
 	// Version 3 of the DLID card spec was published in 2005.  It is currently
 	// (as of 2012) used in Wisconsin.
 
-	if !strings.HasPrefix(licenceData, "DL") {
-		err = errors.New("Missing header in licence data chunk")
+	// Identification cards use an "ID" header instead of "DL" but are
+	// otherwise identical.
+
+	documentType := licenceDataDocumentType(licenceData)
+
+	if documentType == DocumentTypeNone {
+		err = newParseError(ErrSubfileRange, 0, "Missing header in licence data chunk")
 		return
 	}
 
 	licenceData = licenceData[2:]
 
+	if index := strings.Index(licenceData, "\r"); index > -1 {
+		licenceData = licenceData[:index]
+	}
+
 	components := strings.Split(licenceData, "\n")
 
 	license = new(DLIDLicense)
 
+	license.SetDocumentType(documentType)
 	license.SetIssuerId(issuer)
-	license.SetIssuerName(issuers[issuer])
+	license.SetIssuerName(issuers[issuer].Name())
 
 	var dateOfBirth string
 	var expiryDate string
 	var issueDate string
 
+	license.elements = new(Elements)
+
 	for component := range components {
 
 		if len(components[component]) < 3 {
@@ -62,6 +87,8 @@ func parseDataV3(licenceData string, issuer string) (license *DLIDLicense, err e
 		identifier := components[component][0:3]
 		data := components[component][3:]
 
+		license.elements.add(identifier, data)
+
 		data = strings.Trim(data, " ")
 
 		switch identifier {
@@ -74,6 +101,15 @@ func parseDataV3(licenceData string, issuer string) (license *DLIDLicense, err e
 		case "DCD":
 			license.SetEndorsementCodes(data)
 
+		case "DCM":
+			license.SetStandardVehicleClass(data)
+
+		case "DCN":
+			license.SetStandardEndorsementCodes(data)
+
+		case "DCO":
+			license.SetStandardRestrictionCodes(data)
+
 		case "DCS":
 			license.SetLastName(data)
 
@@ -114,9 +150,57 @@ func parseDataV3(licenceData string, issuer string) (license *DLIDLicense, err e
 		case "DAK":
 			license.SetPostal(data)
 
+		case "DAH":
+			license.mailingAddress.SetStreet2(data)
+
+		case "DAL":
+			license.residenceAddress.SetStreet(data)
+
+		case "DAM":
+			license.residenceAddress.SetStreet2(data)
+
+		case "DAN":
+			license.residenceAddress.SetCity(data)
+
+		case "DAO":
+			license.residenceAddress.SetState(data)
+
+		case "DAP":
+			license.residenceAddress.SetPostal(data)
+
+		case "DAF":
+			license.SetNamePrefix(data)
+
+		case "DCI":
+			license.SetPlaceOfBirth(data)
+
+		case "DCL":
+			license.SetRace(parseRace(data))
+
+		case "DCH":
+			license.SetFederalVehicleCodes(data)
+
 		case "DAQ":
 			license.SetCustomerId(data)
 
+		case "DCF":
+			license.SetDocumentDiscriminator(data)
+
+		case "DCK":
+			license.SetInventoryControl(data)
+
+		case "DCJ":
+			license.SetAuditInformation(data)
+
+		case "DBN":
+			license.addAliasFamilyName(data)
+
+		case "DBG":
+			license.addAliasGivenName(data)
+
+		case "DBS":
+			license.addAliasSuffix(data)
+
 		case "DBA":
 			expiryDate = data
 
@@ -135,53 +219,41 @@ func parseDataV3(licenceData string, issuer string) (license *DLIDLicense, err e
 
 		case "DBD":
 			issueDate = data
-		}
-	}
 
-	// At this point we should know the country and the postal code (both are
-	// mandatory fields) so we can undo the desperate mess the standards body
-	// made of the postal code field.
-
-	if license.Country() == "USA" && len(license.Postal()) > 0 {
-
-		// For some reason known only to themselves, the standards guys took
-		// the V1 and 2 postal code standards (code padded to 11 characters with
-		// spaces) and replaced the spaces with zeros if a) the country is "USA"
-		// and b) if the trailing "+4" portion of the postal code is unknown.
-		// Quite what happens to pad Canadian postal codes (they are always 6
-		// alphanumeric characters, like British postal codes) is undocumented.
-		//
-		// We will extract the 5-digit zip and the +4 section.  If the +4 is all
-		// zeros we can discard it.  The last two digits are always useless (the
-		// next version of the spec shortens the field to 9 characters) so we
-		// can ignore them.
-
-		// Naturally, some Texas licences ignore the spec and just use 5
-		// characters if they don't have a +4 section.
-
-		if len(license.Postal()) > 5 {
-			zip := license.Postal()[:5]
-			plus4 := license.Postal()[5:9]
-
-			if plus4 == "0000" {
-				license.SetPostal(zip)
-			} else {
-				license.SetPostal(zip + "+" + plus4)
-			}
+		case "DAU":
+			license.SetHeight(parseHeight(data, false))
+
+		case "DAW":
+			license.SetWeight(parseWeight(data, MeasurementUnitImperial))
+
+		case "DAX":
+			license.SetWeight(parseWeight(data, MeasurementUnitMetric))
+
+		case "DCE":
+			license.SetWeightRange(parseWeightRange(data))
+
+		case "DAY":
+			license.SetEyeColour(parseEyeColour(data))
+
+		case "DAZ":
+			license.SetHairColour(parseHairColour(data))
+
+		default:
+			license.addWarning(WarningUnknownElement, identifier, "Element is not handled by the parser")
 		}
 	}
 
 	// Now we can parse the birth date, too.
-	if len(license.Country()) > 0 {
-		license.SetDateOfBirth(parseDateV3(dateOfBirth, license.Country()))
-		license.SetExpiryDate(parseDateV3(expiryDate, license.Country()))
-		license.SetIssueDate(parseDateV3(issueDate, license.Country()))
-	}
+	format := license.resolveDates(licenceDates{dateOfBirth, issueDate, expiryDate}, issuer, options)
+
+	license.SetDateOfBirth(parseDateV3(dateOfBirth, format))
+	license.SetExpiryDate(parseDateV3(expiryDate, format))
+	license.SetIssueDate(parseDateV3(issueDate, format))
 
 	return
 }
 
-func parseDateV3(data string, country string) time.Time {
+func parseDateV3(data string, format DateFormat) Date {
 
 	// And now we get the payoff for the previous awful decision to switch to
 	// Lumpy Date Format: we're now supporting the international big-endian
@@ -189,53 +261,20 @@ func parseDateV3(data string, country string) time.Time {
 	// lumpy date format *in the same field*.  I can understand that different
 	// versions of a standard don't agree with each other, but now we've got two
 	// implementations of a standard within a single field in a single version
-	// of the standard.  Breathtakingly stupid.
-
-	var day int
-	var month int
-	var year int
-	var err error
-	var location *time.Location
+	// of the standard.  Breathtakingly stupid.  resolveDateFormat works out
+	// which one we've got.
 
-	if country == "USA" {
-		month, err = strconv.Atoi(data[:2])
-
-		if err != nil {
-			return time.Unix(0, 0)
-		}
-
-		day, err = strconv.Atoi(data[2:4])
-
-		if err != nil {
-			return time.Unix(0, 0)
-		}
-
-		year, err = strconv.Atoi(data[4:8])
-
-		if err != nil {
-			return time.Unix(0, 0)
-		}
-	} else {
-		year, err = strconv.Atoi(data[:4])
-
-		if err != nil {
-			return time.Unix(0, 0)
-		}
-
-		month, err = strconv.Atoi(data[4:6])
-
-		if err != nil {
-			return time.Unix(0, 0)
-		}
-
-		day, err = strconv.Atoi(data[6:8])
+	if len(data) == 0 {
+		return Date{}
+	}
 
-		if err != nil {
-			return time.Unix(0, 0)
-		}
+	if len(data) < 8 || format == DateFormatNone {
+		return invalidDate(data)
 	}
 
-	location, err = time.LoadLocation("UTC")
+	if format == DateFormatMMDDCCYY {
+		return dateFromParts(data, data[4:8], data[:2], data[2:4])
+	}
 
-	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, location)
+	return dateFromParts(data, data[:4], data[4:6], data[6:8])
 }
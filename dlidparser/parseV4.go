@@ -1,54 +1,94 @@
 package dlidparser
 
 import (
-	"errors"
 	"strings"
 )
 
-func parseV4(data string, issuer string) (license *DLIDLicense, err error) {
+func parseV4(data string, issuer string, version int, options ParseOptions) (license *DLIDLicense, err error) {
 
-	start, end, err := dataRangeV2(data)
+	subfiles, warnings, err := subfileDirectoryV2(data, options)
 
-	if end >= len(data) {
-		err = errors.New("Payload location does not exist in data")
+	if err != nil {
+		return
 	}
 
-	payload := data[start:end]
+	start, end := dataRange(licenceSubfile(subfiles))
+
+	payload, payloadWarnings, err := licencePayload(data, start, end, options)
 
 	if err != nil {
 		return
 	}
 
-	license, err = parseDataV4(payload, issuer)
+	warnings = append(warnings, payloadWarnings...)
+
+	payload, payloadWarnings = lenientLicenceData(payload, options)
+	warnings = append(warnings, payloadWarnings...)
+
+	license, err = parseDataV4(payload, issuer, version, options)
 
 	if err != nil {
+		err = shiftParseError(err, start)
 		return
 	}
 
+	licenceSubfile(subfiles).elements = license.elements
+	license.SetSubfiles(subfiles)
+	license.warnings = append(warnings, license.warnings...)
+
+	if documentType := subfileDocumentType(subfiles); documentType != DocumentTypeNone {
+		license.SetDocumentType(documentType)
+	}
+
 	return
 }
 
-func parseDataV4(licenceData string, issuer string) (license *DLIDLicense, err error) {
+func parseDataV4(licenceData string, issuer string, version int, options ParseOptions) (license *DLIDLicense, err error) {
 
-	// Version 4 of the DLID card spec was published in 2009.
+	// Version 4 of the DLID card spec was published in 2009.  Versions 5 to 7
+	// followed without changing the elements.  Version 8 was published in
+	// 2013, version 9 in 2016 and version 10 in 2020.  The header and the core
+	// elements are unchanged from version 4, but REAL ID turned the compliance
+	// type and limited-duration indicator into elements that matter, and
+	// version 10 finally admitted that not everyone is male or female.
 
-	if !strings.HasPrefix(licenceData, "DL") {
-		err = errors.New("Missing header in licence data chunk")
+	// Identification cards use an "ID" header instead of "DL" but are
+	// otherwise identical.
+
+	documentType := licenceDataDocumentType(licenceData)
+
+	if documentType == DocumentTypeNone {
+		err = newParseError(ErrSubfileRange, 0, "Missing header in licence data chunk")
 		return
 	}
 
 	licenceData = licenceData[2:]
 
+	// The subfile ends at the segment terminator.  Jurisdictions routinely
+	// get the subfile length wrong, so anything after the terminator is
+	// someone else's data.
+	if index := strings.Index(licenceData, "\r"); index > -1 {
+		licenceData = licenceData[:index]
+	}
+
 	components := strings.Split(licenceData, "\n")
 
 	license = new(DLIDLicense)
 
+	license.SetDocumentType(documentType)
 	license.SetIssuerId(issuer)
-	license.SetIssuerName(issuers[issuer])
+	license.SetIssuerName(issuers[issuer].Name())
 
 	var dateOfBirth string
 	var expiryDate string
 	var issueDate string
+	var cardRevisionDate string
+	var hazmatExpiryDate string
+	var under18Until string
+	var under19Until string
+	var under21Until string
+
+	license.elements = new(Elements)
 
 	for component := range components {
 
@@ -59,6 +99,8 @@ func parseDataV4(licenceData string, issuer string) (license *DLIDLicense, err e
 		identifier := components[component][0:3]
 		data := components[component][3:]
 
+		license.elements.add(identifier, data)
+
 		data = strings.Trim(data, " ")
 
 		switch identifier {
@@ -71,6 +113,15 @@ func parseDataV4(licenceData string, issuer string) (license *DLIDLicense, err e
 		case "DCD":
 			license.SetEndorsementCodes(data)
 
+		case "DCM":
+			license.SetStandardVehicleClass(data)
+
+		case "DCN":
+			license.SetStandardEndorsementCodes(data)
+
+		case "DCO":
+			license.SetStandardRestrictionCodes(data)
+
 		case "DCS":
 			license.SetLastName(data)
 
@@ -99,9 +150,63 @@ func parseDataV4(licenceData string, issuer string) (license *DLIDLicense, err e
 		case "DAK":
 			license.SetPostal(data)
 
+		case "DAH":
+			license.mailingAddress.SetStreet2(data)
+
+		case "DAL":
+			license.residenceAddress.SetStreet(data)
+
+		case "DAM":
+			license.residenceAddress.SetStreet2(data)
+
+		case "DAN":
+			license.residenceAddress.SetCity(data)
+
+		case "DAO":
+			license.residenceAddress.SetState(data)
+
+		case "DAP":
+			license.residenceAddress.SetPostal(data)
+
+		case "DCI":
+			license.SetPlaceOfBirth(data)
+
+		case "DCL":
+			license.SetRace(parseRace(data))
+
+		case "DCH":
+			license.SetFederalVehicleCodes(data)
+
 		case "DAQ":
 			license.SetCustomerId(data)
 
+		case "DCF":
+			license.SetDocumentDiscriminator(data)
+
+		case "DCK":
+			license.SetInventoryControl(data)
+
+		case "DCJ":
+			license.SetAuditInformation(data)
+
+		case "DDE":
+			license.SetLastNameTruncation(parseTruncation(data))
+
+		case "DDF":
+			license.SetFirstNameTruncation(parseTruncation(data))
+
+		case "DDG":
+			license.SetMiddleNameTruncation(parseTruncation(data))
+
+		case "DBN":
+			license.addAliasFamilyName(data)
+
+		case "DBG":
+			license.addAliasGivenName(data)
+
+		case "DBS":
+			license.addAliasSuffix(data)
+
 		case "DBA":
 			expiryDate = data
 
@@ -109,53 +214,103 @@ func parseDataV4(licenceData string, issuer string) (license *DLIDLicense, err e
 			dateOfBirth = data
 
 		case "DBC":
-			switch data {
-			case "1":
+
+			// Version 10 (AAMVA DL/ID Card Design Standard 2020) added "9"
+			// for anyone who is neither male nor female; jurisdictions use
+			// it for "X" on the face of the card.  Earlier versions allow
+			// only "1" and "2".
+
+			switch {
+			case data == "1":
 				license.SetSex(DriverSexMale)
-			case "2":
+			case data == "2":
 				license.SetSex(DriverSexFemale)
+			case data == "9" && version >= 10:
+				license.SetSex(DriverSexNotSpecified)
 			default:
 				license.SetSex(DriverSexNone)
 			}
 
 		case "DBD":
 			issueDate = data
-		}
-	}
 
-	// At this point we should know the country and the postal code (both are
-	// mandatory fields) so we can undo the desperate mess the standards body
-	// made of the postal code field.
-
-	if license.Country() == "USA" && len(license.Postal()) > 0 {
-
-		// Another change to the postal code field!  Surprise!  This time the
-		// standards guys trimmed the field down to 9 characters, which makes
-		// sense because US zip codes are only 9 digits long.  Canadian post
-		// codes are only 6 characters.  Why was the original spec 11 digits?
-		// Because the standards guys are *nuts*.
-		//
-		// We will extract the 5-digit zip and the +4 section.  If the +4 is all
-		// zeros we can discard it.
-
-		if len(license.Postal()) > 5 {
-			zip := license.Postal()[:5]
-			plus4 := license.Postal()[5:9]
-
-			if plus4 == "0000" {
-				license.SetPostal(zip)
-			} else {
-				license.SetPostal(zip + "+" + plus4)
-			}
+		case "DDA":
+			license.SetComplianceType(parseComplianceType(data))
+
+		case "DDB":
+			cardRevisionDate = data
+
+		case "DDC":
+			hazmatExpiryDate = data
+
+		case "DDD":
+			license.SetLimitedDuration(data == "1")
+
+		case "DDK":
+			license.SetOrganDonor(data == "1")
+
+		case "DDL":
+			license.SetVeteran(data == "1")
+
+		case "DDH":
+			under18Until = data
+
+		case "DDI":
+			under19Until = data
+
+		case "DDJ":
+			under21Until = data
+
+		case "DAU":
+			license.SetHeight(parseHeight(data, false))
+
+		case "DAW":
+			license.SetWeight(parseWeight(data, MeasurementUnitImperial))
+
+		case "DAX":
+			license.SetWeight(parseWeight(data, MeasurementUnitMetric))
+
+		case "DCE":
+			license.SetWeightRange(parseWeightRange(data))
+
+		case "DAY":
+			license.SetEyeColour(parseEyeColour(data))
+
+		case "DAZ":
+			license.SetHairColour(parseHairColour(data))
+
+		default:
+			license.addWarning(WarningUnknownElement, identifier, "Element is not handled by the parser")
 		}
 	}
 
 	// Now we can parse the dates, too.
-	if len(license.Country()) > 0 {
-		license.SetDateOfBirth(parseDateV3(dateOfBirth, license.Country()))
-		license.SetExpiryDate(parseDateV3(expiryDate, license.Country()))
-		license.SetIssueDate(parseDateV3(issueDate, license.Country()))
-	}
+	format := license.resolveDates(licenceDates{dateOfBirth, issueDate, expiryDate}, issuer, options)
+
+	license.SetDateOfBirth(parseDateV3(dateOfBirth, format))
+	license.SetExpiryDate(parseDateV3(expiryDate, format))
+	license.SetIssueDate(parseDateV3(issueDate, format))
+	license.SetCardRevisionDate(parseDateV3(cardRevisionDate, format))
+	license.SetHazmatExpiryDate(parseDateV3(hazmatExpiryDate, format))
+	license.SetUnder18Until(parseDateV3(under18Until, format))
+	license.SetUnder19Until(parseDateV3(under19Until, format))
+	license.SetUnder21Until(parseDateV3(under21Until, format))
 
 	return
 }
+
+func parseComplianceType(data string) ComplianceType {
+
+	switch data {
+	case "F":
+		return ComplianceTypeFullyCompliant
+	case "M":
+		return ComplianceTypeMateriallyCompliant
+	case "N":
+		return ComplianceTypeNonCompliant
+	case "":
+		return ComplianceTypeNone
+	}
+
+	return ComplianceTypeUnknown
+}
@@ -5,46 +5,97 @@ import (
 	"strconv"
 )
 
+// Parse extracts the licence data from the contents of a PDF417 barcode.  It
+// is equivalent to calling ParseWithOptions with the default options.
 func Parse(data string) (license *DLIDLicense, err error) {
+	return ParseWithOptions(data, ParseOptions{})
+}
+
+// ParseWithOptions extracts the licence data from the contents of a PDF417
+// barcode, using the options to decide how closely the data must follow the
+// standard.
+func ParseWithOptions(data string, options ParseOptions) (license *DLIDLicense, err error) {
 
 	// This parser is based on standards from here:
 	//
 	// http://www.aamva.org/DL-ID-Card-Design-Standard/
 	//
-	// There are currently 7 standards, and all versions since v1 have used a
+	// There are currently 10 standards, and all versions since v1 have used a
 	// slightly different header definition.
 
 	// The standard says that the 3rd byte in the header should be 0x1e (record
 	// separator) but South Carolina and Pennsylvania use 0x1c (file separator)
-	// because they're special.  We don't even bother checking that byte.
+	// because they're special.  We don't even bother checking that byte unless
+	// we're being strict.
 
 	// PA and CT appear to have used old versions of the spec because they use
 	// "AAMVA" instead of "ANSI " as part of the header.
 
 	if len(data) < 15 {
-		return license, errors.New("Data does not contain expected header")
+		return license, newParseError(ErrHeader, len(data), "Data does not contain expected header")
 	}
 
-	if data[0:2] != "@\n" ||
-		data[3] != '\r' ||
-			(data[4:9] != "ANSI " && data[4:9] != "AAMVA") {
-		return license, errors.New("Data does not contain expected header")
+	if data[0:2] != "@\n" || data[3] != '\r' {
+		return license, newParseError(ErrHeader, 0, "Data does not contain expected header")
+	}
+
+	if options.Mode == ParseModeStrict && data[2] != '\x1e' {
+		return license, newParseError(ErrHeader, 2, "Data does not contain expected header")
+	}
+
+	var warnings []Warning
+
+	if data[2] != '\x1e' {
+		warnings = append(warnings, newWarning(WarningSeparator, "", "Header uses the wrong data element separator"))
+	}
+
+	fileType := data[4:9]
+
+	if options.Mode == ParseModeStrict && fileType != "ANSI " {
+		return license, newParseError(ErrHeader, 4, "Data does not contain expected header")
+	}
+
+	if options.Mode != ParseModeLenient && fileType != "ANSI " && fileType != "AAMVA" {
+		return license, newParseError(ErrHeader, 4, "Data does not contain expected header")
+	}
+
+	if fileType != "ANSI " {
+		warnings = append(warnings, newWarning(WarningFileType, "", "Header uses file type \""+fileType+"\" instead of \"ANSI \""))
 	}
 
 	issuer := data[9:15]
+
+	if len(data) < 17 {
+		parseError := newParseError(ErrHeader, len(data), "Data does not contain a version number")
+		parseError.issuer = issuer
+		return license, parseError
+	}
+
 	version, err := strconv.Atoi(data[15:17])
 
 	if err != nil {
-		return license, errors.New("Data does not contain a version number")
+		parseError := newParseError(ErrHeader, 15, "Data does not contain a version number")
+		parseError.issuer = issuer
+		return license, parseError
 	}
 
-	switch version {
+	// Lenient parsing assumes that anything newer than the versions we know
+	// about is close enough to the latest version to be worth a try.
+
+	parserVersion := version
+
+	if options.Mode == ParseModeLenient && version > 10 {
+		parserVersion = 10
+		warnings = append(warnings, newWarning(WarningVersion, "", "Unsupported version "+strconv.Itoa(version)+" parsed as version 10"))
+	}
+
+	switch parserVersion {
 	case 1:
-		license, err = parseV1(data, issuer)
+		license, err = parseV1(data, issuer, options)
 	case 2:
-		license, err = parseV2(data, issuer)
+		license, err = parseV2(data, issuer, options)
 	case 3:
-		license, err = parseV3(data, issuer)
+		license, err = parseV3(data, issuer, options)
 	case 4:
 		fallthrough
 	case 5:
@@ -52,10 +103,38 @@ func Parse(data string) (license *DLIDLicense, err error) {
 	case 6:
 		fallthrough
 	case 7:
-		license, err = parseV4(data, issuer)
+		fallthrough
+	case 8:
+		fallthrough
+	case 9:
+		fallthrough
+	case 10:
+		license, err = parseV4(data, issuer, parserVersion, options)
 	default:
-		err = errors.New("Unsupported DLID version number")
+		err = newParseError(ErrUnsupportedVersion, 15, "Unsupported DLID version number")
+	}
+
+	if err == nil && options.Mode == ParseModeStrict {
+		err = validateStrict(license, version)
+
+		if err != nil {
+			license = nil
+		}
+	}
+
+	if err != nil {
+		var parseError *ParseError
+
+		if errors.As(err, &parseError) {
+			parseError.version = version
+			parseError.issuer = issuer
+		}
+
+		return
 	}
 
+	license.SetJurisdictionSubfiles(parseJurisdictionSubfiles(license.Subfiles(), issuer))
+	license.warnings = append(warnings, license.warnings...)
+
 	return
 }
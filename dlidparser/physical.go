@@ -0,0 +1,287 @@
+package dlidparser
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+type MeasurementUnit int
+
+const (
+	MeasurementUnitNone MeasurementUnit = iota
+	MeasurementUnitImperial
+	MeasurementUnitMetric
+)
+
+// Height is the height of the licencee, in the unit that the barcode used.
+// Imperial heights are in inches and metric heights are in centimetres.
+type Height struct {
+	value int
+	unit  MeasurementUnit
+}
+
+func NewHeight(value int, unit MeasurementUnit) Height {
+	return Height{value: value, unit: unit}
+}
+
+func (h Height) Value() int {
+	return h.value
+}
+
+func (h Height) Unit() MeasurementUnit {
+	return h.unit
+}
+
+func (h Height) IsZero() bool {
+	return h.unit == MeasurementUnitNone
+}
+
+func (h Height) Inches() int {
+	if h.unit == MeasurementUnitMetric {
+		return int(math.Round(float64(h.value) / 2.54))
+	}
+
+	return h.value
+}
+
+func (h Height) Centimetres() int {
+	if h.unit == MeasurementUnitImperial {
+		return int(math.Round(float64(h.value) * 2.54))
+	}
+
+	return h.value
+}
+
+// Weight is the weight of the licencee, in the unit that the barcode used.
+// Imperial weights are in pounds and metric weights are in kilograms.
+type Weight struct {
+	value int
+	unit  MeasurementUnit
+}
+
+func NewWeight(value int, unit MeasurementUnit) Weight {
+	return Weight{value: value, unit: unit}
+}
+
+func (w Weight) Value() int {
+	return w.value
+}
+
+func (w Weight) Unit() MeasurementUnit {
+	return w.unit
+}
+
+func (w Weight) IsZero() bool {
+	return w.unit == MeasurementUnitNone
+}
+
+func (w Weight) Pounds() int {
+	if w.unit == MeasurementUnitMetric {
+		return int(math.Round(float64(w.value) * 2.20462))
+	}
+
+	return w.value
+}
+
+func (w Weight) Kilograms() int {
+	if w.unit == MeasurementUnitImperial {
+		return int(math.Round(float64(w.value) / 2.20462))
+	}
+
+	return w.value
+}
+
+// WeightRange is the coded weight range used by jurisdictions that don't
+// record an exact weight.  The standard defines ranges 0 to 9; the constants
+// are offset by one so that the zero value means the element was absent.
+type WeightRange int
+
+const (
+	WeightRangeNone WeightRange = iota
+	WeightRange0
+	WeightRange1
+	WeightRange2
+	WeightRange3
+	WeightRange4
+	WeightRange5
+	WeightRange6
+	WeightRange7
+	WeightRange8
+	WeightRange9
+)
+
+var weightRangePounds = [][2]int{
+	{0, 70},
+	{71, 100},
+	{101, 130},
+	{131, 160},
+	{161, 190},
+	{191, 220},
+	{221, 250},
+	{251, 280},
+	{281, 320},
+	{321, 0},
+}
+
+// Pounds returns the lower and upper bounds of the range in pounds.  The
+// upper bound of the heaviest range is zero, because it doesn't have one.
+func (w WeightRange) Pounds() (min int, max int) {
+	if w < WeightRange0 || w > WeightRange9 {
+		return
+	}
+
+	bounds := weightRangePounds[w-WeightRange0]
+
+	return bounds[0], bounds[1]
+}
+
+// These are the ANSI D-20 colour codes.
+type EyeColour int
+
+const (
+	EyeColourNone EyeColour = iota
+	EyeColourBlack
+	EyeColourBlue
+	EyeColourBrown
+	EyeColourGrey
+	EyeColourGreen
+	EyeColourHazel
+	EyeColourMaroon
+	EyeColourPink
+	EyeColourDichromatic
+	EyeColourUnknown
+)
+
+type HairColour int
+
+const (
+	HairColourNone HairColour = iota
+	HairColourBald
+	HairColourBlack
+	HairColourBlond
+	HairColourBrown
+	HairColourGrey
+	HairColourRed
+	HairColourSandy
+	HairColourWhite
+	HairColourUnknown
+)
+
+var eyeColourCodes = map[string]EyeColour{
+	"BLK": EyeColourBlack,
+	"BLU": EyeColourBlue,
+	"BRO": EyeColourBrown,
+	"GRY": EyeColourGrey,
+	"GRN": EyeColourGreen,
+	"HAZ": EyeColourHazel,
+	"MAR": EyeColourMaroon,
+	"PNK": EyeColourPink,
+	"DIC": EyeColourDichromatic,
+	"UNK": EyeColourUnknown,
+
+	// Version 1 licences in the wild use two-letter abbreviations.
+	"BK": EyeColourBlack,
+	"BL": EyeColourBlue,
+	"BR": EyeColourBrown,
+	"GY": EyeColourGrey,
+	"GN": EyeColourGreen,
+	"HZ": EyeColourHazel,
+}
+
+var hairColourCodes = map[string]HairColour{
+	"BAL": HairColourBald,
+	"BLK": HairColourBlack,
+	"BLN": HairColourBlond,
+	"BRO": HairColourBrown,
+	"GRY": HairColourGrey,
+	"RED": HairColourRed,
+	"SDY": HairColourSandy,
+	"WHI": HairColourWhite,
+	"UNK": HairColourUnknown,
+
+	"BK": HairColourBlack,
+	"BN": HairColourBlond,
+	"BR": HairColourBrown,
+	"GY": HairColourGrey,
+	"RD": HairColourRed,
+	"WH": HairColourWhite,
+}
+
+func parseEyeColour(data string) EyeColour {
+	if colour, ok := eyeColourCodes[strings.ToUpper(data)]; ok {
+		return colour
+	}
+
+	return EyeColourNone
+}
+
+func parseHairColour(data string) HairColour {
+	if colour, ok := hairColourCodes[strings.ToUpper(data)]; ok {
+		return colour
+	}
+
+	return HairColourNone
+}
+
+func parseHeight(data string, feetAndInches bool) Height {
+
+	// Heights are written as a number followed by "in" or "cm", in whatever
+	// case and with whatever padding the jurisdiction fancied.  V1 used a
+	// three digit feet-and-inches format without units, so "509" is 5'09".
+
+	data = strings.ToLower(strings.Replace(data, " ", "", -1))
+
+	unit := MeasurementUnitImperial
+
+	if strings.HasSuffix(data, "cm") {
+		unit = MeasurementUnitMetric
+		data = strings.TrimSuffix(data, "cm")
+	} else if strings.HasSuffix(data, "in") {
+		data = strings.TrimSuffix(data, "in")
+	} else if feetAndInches && len(data) == 3 {
+		feet, err := strconv.Atoi(data[:1])
+
+		if err != nil {
+			return Height{}
+		}
+
+		inches, err := strconv.Atoi(data[1:])
+
+		if err != nil || inches > 11 {
+			return Height{}
+		}
+
+		return Height{value: feet*12 + inches, unit: MeasurementUnitImperial}
+	}
+
+	value, err := strconv.Atoi(data)
+
+	if err != nil || value <= 0 {
+		return Height{}
+	}
+
+	return Height{value: value, unit: unit}
+}
+
+func parseWeight(data string, unit MeasurementUnit) Weight {
+
+	value, err := strconv.Atoi(strings.Trim(data, " "))
+
+	if err != nil || value <= 0 {
+		return Weight{}
+	}
+
+	return Weight{value: value, unit: unit}
+}
+
+func parseWeightRange(data string) WeightRange {
+
+	value, err := strconv.Atoi(data)
+
+	if err != nil || len(data) != 1 {
+		return WeightRangeNone
+	}
+
+	return WeightRange0 + WeightRange(value)
+}
@@ -0,0 +1,158 @@
+package dlidparser
+
+import (
+	"strings"
+)
+
+// PostalCode is a US zip code or a Canadian postal code.  Each version of the
+// standard has written these differently, and each jurisdiction has ignored
+// whichever version it was meant to follow, so the raw value is kept
+// alongside the parts that could be recovered from it.
+type PostalCode struct {
+	raw   string
+	zip5  string
+	plus4 string
+	fsa   string
+	ldu   string
+}
+
+// ParsePostalCode makes sense of a postal code in any of the formats that
+// turn up on cards.
+func ParsePostalCode(data string) PostalCode {
+
+	postal := PostalCode{raw: data}
+
+	// Canadian postal codes are always 6 alphanumeric characters, like
+	// British postal codes, optionally split in the middle by a space.
+	// Quite how they are meant to be padded is undocumented.
+
+	compact := strings.ToUpper(strings.Replace(strings.Trim(data, " "), " ", "", -1))
+
+	if len(compact) == 6 && isCanadianPostalCode(compact) {
+		postal.fsa = compact[:3]
+		postal.ldu = compact[3:]
+		return postal
+	}
+
+	// US zip codes come as 5 digits, 9 digits all smooshed together, 5 and 4
+	// digits separated by "-" or "+", or (from version 3) 11 digits padded
+	// with zeros where the +4 is unknown and two useless digits on the end.
+
+	digits := strings.Map(func(r rune) rune {
+		if r == '-' || r == '+' || r == ' ' {
+			return -1
+		}
+
+		return r
+	}, compact)
+
+	if !isDigits(digits) || (len(digits) != 5 && len(digits) != 9 && len(digits) != 11) {
+		return postal
+	}
+
+	postal.zip5 = digits[:5]
+
+	if len(digits) > 5 && digits[5:9] != "0000" {
+		postal.plus4 = digits[5:9]
+	}
+
+	return postal
+}
+
+// Raw is the postal code exactly as it was given to ParsePostalCode, minus
+// any padding.
+func (p PostalCode) Raw() string {
+	return strings.Trim(p.raw, " ")
+}
+
+func (p PostalCode) ZIP5() string {
+	return p.zip5
+}
+
+func (p PostalCode) Plus4() string {
+	return p.plus4
+}
+
+// FSA is the forward sortation area: the first half of a Canadian postal
+// code.
+func (p PostalCode) FSA() string {
+	return p.fsa
+}
+
+// LDU is the local delivery unit: the second half of a Canadian postal code.
+func (p PostalCode) LDU() string {
+	return p.ldu
+}
+
+func (p PostalCode) IsUS() bool {
+	return len(p.zip5) > 0
+}
+
+func (p PostalCode) IsCanadian() bool {
+	return len(p.fsa) > 0
+}
+
+// IsValid reports whether the postal code could be understood as either a US
+// or a Canadian code.
+func (p PostalCode) IsValid() bool {
+	return p.IsUS() || p.IsCanadian()
+}
+
+func (p PostalCode) IsZero() bool {
+	return len(p.raw) == 0
+}
+
+// String returns the canonical form of the postal code: "12345", "12345+6789"
+// or "A1A 1A1".  Postal codes that couldn't be understood are returned as they
+// were found.
+func (p PostalCode) String() string {
+
+	switch {
+	case p.IsCanadian():
+		return p.fsa + " " + p.ldu
+	case len(p.plus4) > 0:
+		return p.zip5 + "+" + p.plus4
+	case p.IsUS():
+		return p.zip5
+	}
+
+	return p.Raw()
+}
+
+func isCanadianPostalCode(data string) bool {
+
+	// Canada Post never uses D, F, I, O, Q or U, to avoid confusion with
+	// other characters, and doesn't start codes with W or Z either.
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+
+		if i%2 == 1 {
+			if c < '0' || c > '9' {
+				return false
+			}
+
+			continue
+		}
+
+		if c < 'A' || c > 'Z' || strings.IndexByte("DFIOQU", c) > -1 {
+			return false
+		}
+
+		if i == 0 && (c == 'W' || c == 'Z') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isDigits(data string) bool {
+	for i := 0; i < len(data); i++ {
+		if data[i] < '0' || data[i] > '9' {
+			return false
+		}
+	}
+
+	return len(data) > 0
+}
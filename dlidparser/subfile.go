@@ -0,0 +1,257 @@
+package dlidparser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Subfile describes a single entry in the subfile directory that follows the
+// header.  The type is the two-character subfile designator ("DL", "ID", or a
+// jurisdiction-specific "Z?" designator), and the offset and length are the
+// values stored in the directory.  The data is the raw content of the subfile.
+type Subfile struct {
+	subfileType string
+	offset      int
+	length      int
+	data        string
+	elements    *Elements
+}
+
+func (s *Subfile) Type() string {
+	return s.subfileType
+}
+
+func (s *Subfile) Offset() int {
+	return s.offset
+}
+
+func (s *Subfile) Length() int {
+	return s.length
+}
+
+func (s *Subfile) Data() string {
+	return s.data
+}
+
+// Elements returns the data elements in the subfile.  For the licence subfile
+// these are the elements as the parser saw them, after any adjustments for
+// jurisdictions that mislabel their data.
+func (s *Subfile) Elements() *Elements {
+	return s.elements
+}
+
+// Each entry in the subfile directory is exactly 10 bytes long: a 2-byte
+// subfile type, a 4-byte offset and a 4-byte length.
+const subfileEntryLength int = 10
+
+func subfileDirectoryV1(data string, options ParseOptions) (subfiles []*Subfile, warnings []Warning, err error) {
+
+	// V1 headers have no jurisdiction version number, so the number of
+	// entries immediately follows the version number.
+	return parseSubfileDirectory(data, 17, options)
+}
+
+func subfileDirectoryV2(data string, options ParseOptions) (subfiles []*Subfile, warnings []Warning, err error) {
+
+	// All later versions put a 2-byte jurisdiction version number between the
+	// version number and the number of entries.
+	return parseSubfileDirectory(data, 19, options)
+}
+
+func parseSubfileDirectory(data string, countStart int, options ParseOptions) (subfiles []*Subfile, warnings []Warning, err error) {
+
+	// The header tells us how many entries are in the directory.  If the
+	// number is missing or nonsensical we fall back to reading a single entry,
+	// which is all that this parser ever used to read.  Unless we're being
+	// strict, in which case it's just wrong.
+
+	count := 1
+
+	if len(data) >= countStart+2 {
+		entries, countErr := strconv.Atoi(data[countStart : countStart+2])
+
+		if countErr == nil && entries > 0 {
+			count = entries
+		} else if options.Mode == ParseModeStrict {
+			err = newParseError(ErrHeader, countStart, "Data contains malformed number of entries")
+			return
+		}
+	}
+
+	directoryStart := countStart + 2
+
+	for i := 0; i < count; i++ {
+		subfile, entryErr := parseSubfileEntry(data, directoryStart+i*subfileEntryLength)
+
+		if entryErr != nil {
+
+			// Without the first entry we can't find the licence data at all,
+			// but if a later entry is broken we still have something to work
+			// with.  Jurisdictions have been known to lie about the number of
+			// entries.
+			if i == 0 || options.Mode == ParseModeStrict {
+				err = entryErr
+				return
+			}
+
+			warnings = append(warnings, newWarning(WarningSubfileCount, "", "Subfile directory contains fewer entries than its header claims"))
+
+			break
+		}
+
+		subfiles = append(subfiles, subfile)
+	}
+
+	directoryEnd := directoryStart + len(subfiles)*subfileEntryLength
+
+	for _, subfile := range subfiles {
+		var relocated bool
+
+		subfile.data, relocated = locateSubfileData(data, subfile, directoryEnd)
+		subfile.elements = parseElements(subfile.data, subfile.Type())
+
+		if relocated && options.Mode == ParseModeStrict {
+			subfiles = nil
+			err = newParseError(ErrSubfileRange, subfile.offset, "Subfile "+subfile.Type()+" is not at the offset given in the directory")
+			return
+		}
+
+		if relocated {
+			warnings = append(warnings, newWarning(WarningSubfileOffset, "", "Subfile "+subfile.Type()+" is not at the offset given in the directory"))
+		}
+	}
+
+	return
+}
+
+func parseSubfileEntry(data string, entryStart int) (subfile *Subfile, err error) {
+
+	if len(data) < entryStart+subfileEntryLength {
+		err = newParseError(ErrSubfileRange, entryStart, "Data contains malformed payload location")
+		return
+	}
+
+	entry := data[entryStart : entryStart+subfileEntryLength]
+
+	subfile = new(Subfile)
+	subfile.subfileType = entry[0:2]
+
+	subfile.offset, err = strconv.Atoi(entry[2:6])
+
+	if err != nil {
+		err = newParseError(ErrSubfileRange, entryStart+2, "Data contains malformed payload location")
+		return
+	}
+
+	subfile.length, err = strconv.Atoi(entry[6:10])
+
+	if err != nil {
+		err = newParseError(ErrSubfileRange, entryStart+6, "Data contains malformed payload length")
+		return
+	}
+
+	return
+}
+
+func locateSubfileData(data string, subfile *Subfile, directoryEnd int) (subfileData string, relocated bool) {
+
+	start := subfile.offset
+
+	// Few jurisdictions manage to get the offsets of their jurisdiction-
+	// specific subfiles right.  If the directory doesn't point at something
+	// that starts with the subfile designator, we'll look for the designator
+	// immediately after a segment terminator instead.
+
+	if start < 0 || start >= len(data) || !strings.HasPrefix(data[start:], subfile.subfileType) {
+		if directoryEnd <= len(data) {
+			index := strings.Index(data[directoryEnd:], "\r"+subfile.subfileType)
+
+			if index > -1 {
+				start = directoryEnd + index + 1
+				relocated = true
+			}
+		}
+	}
+
+	if start < 0 || start >= len(data) {
+		return
+	}
+
+	end := start + subfile.length
+
+	if end > len(data) || end < start {
+		end = len(data)
+	}
+
+	subfileData = data[start:end]
+
+	return
+}
+
+func licenceSubfile(subfiles []*Subfile) *Subfile {
+
+	// A card can be both a driver license and an identification card, in
+	// which case it has both subfiles.  They hold the same personal data, so
+	// we prefer the licence and use the ID subfile only if there is nothing
+	// else.
+
+	for _, subfile := range subfiles {
+		if subfile.Type() == "DL" {
+			return subfile
+		}
+	}
+
+	for _, subfile := range subfiles {
+		if subfile.Type() == "ID" {
+			return subfile
+		}
+	}
+
+	// Plenty of jurisdictions put the licence data first without bothering to
+	// label it properly, so the first entry is the best guess we have.
+	return subfiles[0]
+}
+
+func dataRange(subfile *Subfile) (start int, end int) {
+	start = subfile.Offset()
+	end = start + subfile.Length()
+
+	return
+}
+
+func subfileDocumentType(subfiles []*Subfile) DocumentType {
+
+	hasLicence := false
+	hasIdentification := false
+
+	for _, subfile := range subfiles {
+		switch subfile.Type() {
+		case "DL":
+			hasLicence = true
+		case "ID":
+			hasIdentification = true
+		}
+	}
+
+	switch {
+	case hasLicence && hasIdentification:
+		return DocumentTypeDriverLicenseAndIdentificationCard
+	case hasLicence:
+		return DocumentTypeDriverLicense
+	case hasIdentification:
+		return DocumentTypeIdentificationCard
+	}
+
+	return DocumentTypeNone
+}
+
+func licenceDataDocumentType(licenceData string) DocumentType {
+
+	if strings.HasPrefix(licenceData, "DL") {
+		return DocumentTypeDriverLicense
+	} else if strings.HasPrefix(licenceData, "ID") {
+		return DocumentTypeIdentificationCard
+	}
+
+	return DocumentTypeNone
+}
@@ -0,0 +1,163 @@
+package dlidparser
+
+import (
+	"strings"
+)
+
+// VehicleCodeType says which of the three code fields a vehicle code came
+// from, as the same letter means different things in each.
+type VehicleCodeType int
+
+const (
+	VehicleCodeTypeClass VehicleCodeType = iota
+	VehicleCodeTypeRestriction
+	VehicleCodeTypeEndorsement
+)
+
+// VehicleCode is a single vehicle class, restriction or endorsement code along
+// with a human-readable description.  The description is empty if the code
+// isn't in the standard tables and the issuer has no override for it.
+type VehicleCode struct {
+	code        string
+	description string
+}
+
+func (v VehicleCode) Code() string {
+	return v.code
+}
+
+func (v VehicleCode) Description() string {
+	return v.description
+}
+
+// These are the codes defined by the AAMVA standard.  Jurisdictions are free
+// to invent their own, and frequently do, so anything not listed here needs a
+// per-issuer override.
+
+var standardVehicleClasses = map[string]string{
+	"A": "Combination vehicle over 26,001 lbs towing over 10,000 lbs",
+	"B": "Heavy straight vehicle over 26,001 lbs",
+	"C": "Small vehicle carrying hazardous materials or 16 or more passengers",
+	"M": "Motorcycle",
+
+	// Not part of the standard, but used by almost every jurisdiction for a
+	// regular licence.
+	"D": "Regular operator",
+}
+
+var standardRestrictions = map[string]string{
+	"B": "Corrective lenses",
+	"C": "Mechanical devices",
+	"D": "Prosthetic aid",
+	"E": "Automatic transmission",
+	"F": "Outside mirror",
+	"G": "Daylight only",
+	"H": "Employment only",
+	"I": "Limited other",
+	"J": "Other",
+	"K": "CDL intrastate only",
+	"L": "Vehicles without air brakes",
+	"M": "Except class A bus",
+	"N": "Except class A and class B bus",
+	"O": "Except tractor-trailer",
+	"P": "No passengers in CMV bus",
+	"V": "Medical variance",
+	"W": "Farm waiver",
+	"X": "No cargo in CMV tank vehicle",
+	"Z": "No full air brake",
+}
+
+var standardEndorsements = map[string]string{
+	"H": "Hazardous materials",
+	"N": "Tank vehicle",
+	"P": "Passenger",
+	"S": "School bus",
+	"T": "Double and triple trailers",
+	"X": "Tank vehicle with hazardous materials",
+}
+
+var standardVehicleCodes = map[VehicleCodeType]map[string]string{
+	VehicleCodeTypeClass:       standardVehicleClasses,
+	VehicleCodeTypeRestriction: standardRestrictions,
+	VehicleCodeTypeEndorsement: standardEndorsements,
+}
+
+var vehicleCodeOverrides = map[string]map[VehicleCodeType]map[string]string{}
+
+// RegisterVehicleCodes sets the descriptions of the jurisdiction-specific
+// vehicle codes used by the given issuer.  They take precedence over the
+// standard descriptions and replace any codes previously registered for the
+// same issuer and type.  Registering nil removes them.  As with jurisdiction
+// decoders, codes should be registered before any parsing starts.
+func RegisterVehicleCodes(issuer string, codeType VehicleCodeType, codes map[string]string) {
+
+	if codes == nil {
+		delete(vehicleCodeOverrides[issuer], codeType)
+		return
+	}
+
+	if vehicleCodeOverrides[issuer] == nil {
+		vehicleCodeOverrides[issuer] = map[VehicleCodeType]map[string]string{}
+	}
+
+	vehicleCodeOverrides[issuer][codeType] = codes
+}
+
+// DecodeVehicleCodes splits a vehicle class, restriction or endorsement field
+// into individual codes and looks up their descriptions.  Pass an empty issuer
+// to use only the standard tables.
+func DecodeVehicleCodes(data string, codeType VehicleCodeType, issuer string) (codes []VehicleCode) {
+
+	overrides := vehicleCodeOverrides[issuer][codeType]
+
+	for _, code := range splitVehicleCodes(data, overrides) {
+		description, ok := overrides[code]
+
+		if !ok {
+			description = standardVehicleCodes[codeType][code]
+		}
+
+		codes = append(codes, VehicleCode{code: code, description: description})
+	}
+
+	return
+}
+
+func splitVehicleCodes(data string, overrides map[string]string) (codes []string) {
+
+	// The standard never says how multiple codes should be written.  Some
+	// jurisdictions separate them with commas or spaces; most just run the
+	// letters together, which only works because the standard codes are all
+	// one character long.  "NONE" is popular for an empty field.
+
+	data = strings.ToUpper(strings.Trim(data, " "))
+
+	if len(data) == 0 || data == "NONE" {
+		return nil
+	}
+
+	if strings.ContainsAny(data, ", /") {
+		return strings.FieldsFunc(data, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '/'
+		})
+	}
+
+	// Jurisdictions that invent longer codes still run them together, so
+	// we take the longest of the issuer's codes that fits at each point and
+	// fall back to a single character.
+
+	for len(data) > 0 {
+		length := 1
+
+		for code := range overrides {
+			if len(code) > length && strings.HasPrefix(data, code) {
+				length = len(code)
+			}
+		}
+
+		codes = append(codes, data[:length])
+		data = data[length:]
+	}
+
+	return
+}
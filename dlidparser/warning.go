@@ -0,0 +1,60 @@
+package dlidparser
+
+// WarningCode identifies the kind of problem that a Warning describes.  The
+// codes are stable strings so that they can be logged and counted.
+type WarningCode string
+
+const (
+	WarningSeparator        WarningCode = "separator"
+	WarningFileType         WarningCode = "file-type"
+	WarningSubfileCount     WarningCode = "subfile-count"
+	WarningSubfileOffset    WarningCode = "subfile-offset"
+	WarningSubfileRange     WarningCode = "subfile-range"
+	WarningSubfileHeader    WarningCode = "subfile-header"
+	WarningVersion          WarningCode = "version"
+	WarningNameOrder        WarningCode = "name-order"
+	WarningNameDelimiter    WarningCode = "name-delimiter"
+	WarningResidenceAddress WarningCode = "residence-address"
+	WarningUnknownElement   WarningCode = "unknown-element"
+	WarningDateFormat       WarningCode = "date-format"
+	WarningDateOrder        WarningCode = "date-order"
+
+	// These are reported by CheckConsistency rather than by the parser.
+	WarningUnknownIssuer    WarningCode = "unknown-issuer"
+	WarningIssuerState      WarningCode = "issuer-state"
+	WarningIssuerCountry    WarningCode = "issuer-country"
+	WarningIssuerDateFormat WarningCode = "issuer-date-format"
+)
+
+// Warning describes a deviation from the standard that the parser worked
+// around.  The element is the ID of the data element involved, if there is
+// one.
+type Warning struct {
+	code    WarningCode
+	element string
+	message string
+}
+
+func newWarning(code WarningCode, element string, message string) Warning {
+	return Warning{code: code, element: element, message: message}
+}
+
+func (w Warning) Code() WarningCode {
+	return w.code
+}
+
+func (w Warning) Element() string {
+	return w.element
+}
+
+func (w Warning) Message() string {
+	return w.message
+}
+
+func (w Warning) String() string {
+	if len(w.element) > 0 {
+		return string(w.code) + " (" + w.element + "): " + w.message
+	}
+
+	return string(w.code) + ": " + w.message
+}